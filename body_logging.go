@@ -0,0 +1,209 @@
+package minimal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// Fields whose names contain any of these are always redacted from logged bodies, such as "client_secret" or
+// "newPassword". Names are compared ignoring case, underscores and dashes.
+var defaultRedactedFields = []string{
+	"password", "passwd", "secret", "token", "key", "authorization", "credential",
+}
+
+type BodyLogConfig struct {
+	// MaxBytes limits how much of each body is logged. Defaults to 4096.
+	MaxBytes int
+
+	// MaxBufferBytes limits how much of each body is held in memory for logging. Larger bodies can't be redacted
+	// reliably, so they aren't logged at all. Defaults to 65536.
+	MaxBufferBytes int
+
+	// Redact lists field names redacted in addition to the defaults (passwords, tokens, et.c.). Like the defaults,
+	// fields containing them are redacted too.
+	Redact []string
+}
+
+/*
+BodyLogging logs request and response bodies, which helps when diagnosing a client integration. It is meant for
+debugging only, either for a single resource or for the whole server using Config.DebugBodyLogging:
+
+	api.Middlewares(minimal.BodyLogging(minimal.BodyLogConfig{Redact: []string{"ssn"}}))
+
+Values of secret looking fields are redacted in JSON and form bodies. Bodies of other content types aren't logged.
+*/
+func BodyLogging(config BodyLogConfig) echo.MiddlewareFunc {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 4096
+	}
+
+	if config.MaxBufferBytes <= 0 {
+		config.MaxBufferBytes = 64 << 10
+	}
+
+	fields := append(append([]string{}, defaultRedactedFields...), config.Redact...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			reqBody := &cappedBuffer{max: config.MaxBufferBytes}
+			if req.Body != nil {
+				req.Body = teeReadCloser{Reader: io.TeeReader(req.Body, reqBody), Closer: req.Body}
+			}
+
+			resp := c.Response()
+			resBody := &cappedBuffer{max: config.MaxBufferBytes}
+			writer := &bodyLogWriter{ResponseWriter: resp.Writer, body: resBody}
+			resp.Writer = writer
+			defer func() {
+				resp.Writer = writer.ResponseWriter
+			}()
+
+			err := next(c)
+
+			log.Infof("%s %s request body: %s", req.Method, req.URL.Path,
+				reqBody.redacted(req.Header.Get(echo.HeaderContentType), fields, config.MaxBytes))
+			log.Infof("%s %s response body: %s", req.Method, req.URL.Path,
+				resBody.redacted(resp.Header().Get(echo.HeaderContentType), fields, config.MaxBytes))
+
+			return err
+		}
+	}
+}
+
+// cappedBuffer keeps up to max bytes of what is written to it, remembering whether more was written.
+type cappedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); len(p) > room {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p)
+}
+
+func (b *cappedBuffer) redacted(contentType string, fields []string, maxBytes int) string {
+	if b.truncated {
+		return fmt.Sprintf("<body of more than %d bytes omitted>", b.max)
+	}
+
+	return redactBody(contentType, b.Bytes(), fields, maxBytes)
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter copies the response body into body as it is written.
+type bodyLogWriter struct {
+	http.ResponseWriter
+	body *cappedBuffer
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	_, _ = w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLogWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// redactBody returns a loggable representation of body, with the values of the given fields redacted.
+func redactBody(contentType string, body []byte, fields []string, maxBytes int) string {
+	if len(body) == 0 {
+		return "<empty>"
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var out string
+	switch mediaType {
+	case echo.MIMEApplicationJSON:
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "<malformed JSON>"
+		}
+
+		b, _ := json.Marshal(redactValue(v, fields))
+		out = string(b)
+	case echo.MIMEApplicationForm:
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "<malformed form>"
+		}
+
+		for key := range values {
+			if isRedacted(key, fields) {
+				values[key] = []string{redacted}
+			}
+		}
+		out = values.Encode()
+	default:
+		return "<" + mediaType + " body omitted>"
+	}
+
+	if len(out) > maxBytes {
+		return out[:maxBytes] + "...(truncated)"
+	}
+
+	return out
+}
+
+func redactValue(v any, fields []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if isRedacted(key, fields) {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(value, fields)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactValue(value, fields)
+		}
+	}
+
+	return v
+}
+
+// isRedacted reports whether key contains any of fields, ignoring case, underscores and dashes.
+func isRedacted(key string, fields []string) bool {
+	key = normalizeFieldName(key)
+	for _, field := range fields {
+		if field = normalizeFieldName(field); field != "" && strings.Contains(key, field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var fieldNameSeparators = strings.NewReplacer("_", "", "-", "")
+
+func normalizeFieldName(name string) string {
+	return strings.ToLower(fieldNameSeparators.Replace(name))
+}
@@ -0,0 +1,108 @@
+package minimal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kaiaverkvist/minimal/res"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrorNoFilter = errors.New("bulk operations require a filter")
+)
+
+type BulkWriteResponse struct {
+	RowsAffected int64
+}
+
+// bulkWrite applies the same partial update to every row matching the filter[column]=value query parameters.
+func (r *Resource[T]) bulkWrite(c echo.Context) error {
+	if r.canBulkWrite != nil {
+		if !r.canBulkWrite(c) {
+			return r.fail(c, OperationBulkWrite, http.StatusForbidden, ErrorNoResourceAccess)
+		}
+	}
+
+	filters, err := bulkFilters(c, r.bulkFilterFields)
+	if err != nil {
+		return r.fail(c, OperationBulkWrite, http.StatusBadRequest, err)
+	}
+
+	// Mass-mutating the entire table is never what you want from this endpoint.
+	if len(filters) == 0 {
+		return r.fail(c, OperationBulkWrite, http.StatusBadRequest, ErrorNoFilter)
+	}
+
+	updates := map[string]any{}
+	if err := c.Bind(&updates); err != nil {
+		log.Error("Binding failed: ", err)
+		return r.fail(c, OperationBulkWrite, http.StatusBadRequest, bindError(err))
+	}
+
+	for column := range updates {
+		if !contains(r.bulkWritableFields, column) {
+			return r.fail(c, OperationBulkWrite, http.StatusBadRequest, fmt.Errorf("%w: %s can't be bulk written", ErrorInvalidData, column))
+		}
+	}
+
+	if len(updates) == 0 {
+		return res.Ok(c, BulkWriteResponse{})
+	}
+
+	tx := r.scoped(c, r.query(c)).Model(new(T)).Clauses(filters...).Updates(updates)
+	if tx.Error != nil {
+		log.Errorf("Could not bulk write for resource %s: %s", reflect.TypeOf(r), tx.Error)
+		return r.fail(c, OperationBulkWrite, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	return res.Ok(c, BulkWriteResponse{RowsAffected: tx.RowsAffected})
+}
+
+// bulkFilters turns filter[column]=value query parameters into where clauses. Multiple values for the same column
+// become an IN clause. Filtering on columns outside of allowed is an error.
+func bulkFilters(c echo.Context, allowed []string) ([]clause.Expression, error) {
+	var filters []clause.Expression
+	for key, values := range c.QueryParams() {
+		if !strings.HasPrefix(key, "filter[") || !strings.HasSuffix(key, "]") {
+			continue
+		}
+
+		column := key[len("filter[") : len(key)-1]
+		if !contains(allowed, column) {
+			return nil, fmt.Errorf("%w: can't filter on %s", ErrorInvalidData, column)
+		}
+
+		filters = append(filters, whereIn(column, values))
+	}
+
+	return filters, nil
+}
+
+// whereIn matches column against a single value, or any of the values when there are several.
+func whereIn(column string, values []string) clause.Expression {
+	if len(values) == 1 {
+		return clause.Eq{Column: clause.Column{Name: column}, Value: values[0]}
+	}
+
+	in := make([]any, len(values))
+	for i, v := range values {
+		in[i] = v
+	}
+
+	return clause.IN{Column: clause.Column{Name: column}, Values: in}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,191 @@
+package minimal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kaiaverkvist/minimal/res"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+	"net/http"
+	"reflect"
+)
+
+// BulkCreateResponse counts the entities created by bulk create. When creating with ?partial=true, Results holds the
+// outcome of every item, in the order they were sent.
+type BulkCreateResponse struct {
+	Created int
+	Failed  int
+	Results []BulkCreateResult `json:",omitempty"`
+}
+
+type BulkCreateResult struct {
+	Index   int
+	Success bool
+	Error   string `json:",omitempty"`
+}
+
+// bulkCreate creates every entity in the JSON array of create bind types in the body. By default they are created in
+// a single transaction which is rolled back if any of them fails. With ?partial=true, or ?atomic=false, every entity
+// is created on its own and the response is a 207 Multi-Status listing which ones failed.
+func (r *Resource[T]) bulkCreate(c echo.Context) error {
+	if r.canCreate != nil {
+		if !r.canCreate(c) {
+			return r.fail(c, OperationBulkCreate, http.StatusForbidden, ErrorNoResourceAccess)
+		}
+	}
+
+	if r.createBindType == nil {
+		log.Error("Cannot bulk create without a bind type set up. Call SetCreateBindType.")
+		return r.fail(c, OperationBulkCreate, http.StatusInternalServerError, ErrorNoBindType)
+	}
+
+	boundPtr := reflect.New(reflect.SliceOf(reflect.TypeOf(r.createBindType)))
+	if err := c.Bind(boundPtr.Interface()); err != nil {
+		log.Error("Binding failed: ", err)
+		return r.fail(c, OperationBulkCreate, http.StatusBadRequest, bindError(err))
+	}
+	bound := boundPtr.Elem()
+
+	if c.QueryParam("partial") == "true" || c.QueryParam("atomic") == "false" {
+		return r.bulkCreatePartial(c, bound)
+	}
+
+	models := make([]T, bound.Len())
+	for i := range models {
+		if err := r.prepareBulkItem(c, bound.Index(i).Addr().Interface(), &models[i]); err != nil {
+			return r.fail(c, OperationBulkCreate, http.StatusBadRequest, fmt.Errorf("item %d: %w", i, err))
+		}
+	}
+
+	if len(models) == 0 {
+		return res.Ok(c, BulkCreateResponse{})
+	}
+
+	// The hooks share the transaction with the inserts, so a failing item rolls back what the earlier hooks did.
+	var beforeErr error
+	err := r.bulkTransaction(c, func(tx *gorm.DB) error {
+		for i := range models {
+			if beforeErr = r.beforeBulkItem(c, &models[i]); beforeErr != nil {
+				beforeErr = fmt.Errorf("item %d: %w", i, beforeErr)
+				return beforeErr
+			}
+
+			if err := checkUnique(tx, &models[i], r.uniqueSets); err != nil {
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+
+		if err := tx.Create(&models).Error; err != nil {
+			return err
+		}
+
+		return r.afterBulkItems(c, models)
+	})
+
+	if err != nil {
+		if beforeErr != nil {
+			return r.fail(c, OperationBulkCreate, http.StatusBadRequest, beforeErr)
+		}
+
+		if errors.Is(err, ErrorConflict) {
+			return r.fail(c, OperationBulkCreate, http.StatusConflict, err)
+		}
+
+		log.Errorf("Could not bulk create for resource %s: %s", reflect.TypeOf(r), err)
+		return r.fail(c, OperationBulkCreate, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	return res.Ok(c, BulkCreateResponse{Created: len(models)})
+}
+
+// bulkCreatePartial creates every bound item in its own transaction, carrying on past the ones which fail.
+func (r *Resource[T]) bulkCreatePartial(c echo.Context, bound reflect.Value) error {
+	response := BulkCreateResponse{Results: make([]BulkCreateResult, bound.Len())}
+	for i := range response.Results {
+		var model T
+		err := r.prepareBulkItem(c, bound.Index(i).Addr().Interface(), &model)
+		if err == nil {
+			err = r.bulkTransaction(c, func(tx *gorm.DB) error {
+				if err := r.beforeBulkItem(c, &model); err != nil {
+					return err
+				}
+
+				if err := checkUnique(tx, &model, r.uniqueSets); err != nil {
+					return err
+				}
+
+				if err := tx.Create(&model).Error; err != nil {
+					log.Errorf("Could not bulk create item %d for resource %s: %s", i, reflect.TypeOf(r), err)
+					return ErrorDatabase
+				}
+
+				return r.afterBulkItems(c, []T{model})
+			})
+		}
+
+		response.Results[i] = BulkCreateResult{Index: i, Success: err == nil}
+		if err != nil {
+			response.Results[i].Error = err.Error()
+			response.Failed++
+		} else {
+			response.Created++
+		}
+	}
+
+	return res.OkCode(c, http.StatusMultiStatus, response)
+}
+
+// prepareBulkItem validates bound and patches it onto model, running the same steps as a single create would before
+// its transaction starts.
+func (r *Resource[T]) prepareBulkItem(c echo.Context, bound any, model *T) error {
+	if err := r.validate(bound); err != nil {
+		return err
+	}
+
+	if err := mergeStruct(model, bound, r.ignorePatchMismatches); err != nil {
+		return err
+	}
+
+	if r.idGenerator != nil {
+		if err := generateID(r.query(c), model, r.idGenerator); err != nil {
+			log.Errorf("Could not generate id for resource %s: %s", reflect.TypeOf(r), err)
+			return ErrorInvalidID
+		}
+	}
+
+	return nil
+}
+
+// beforeBulkItem runs the before create hook for an entity, within the transaction creating it.
+func (r *Resource[T]) beforeBulkItem(c echo.Context, model *T) error {
+	if r.beforeCreate == nil {
+		return nil
+	}
+
+	return r.beforeCreate(c, model)
+}
+
+// afterBulkItems runs the after create hook for every created entity, unless dry running.
+func (r *Resource[T]) afterBulkItems(c echo.Context, models []T) error {
+	if r.afterCreate == nil || isDryRun(c) {
+		return nil
+	}
+
+	for i := range models {
+		if err := r.afterCreate(c, &models[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bulkTransaction runs f in a transaction, which is always rolled back when dry running.
+func (r *Resource[T]) bulkTransaction(c echo.Context, f func(tx *gorm.DB) error) error {
+	if isDryRun(c) {
+		return dryRun(r.query(c), f)
+	}
+
+	return r.query(c).Transaction(f)
+}
@@ -0,0 +1,246 @@
+package minimal
+
+import (
+	"bytes"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores byte values by key, and can be backed by e.g. Redis.
+type Cache interface {
+	// Get returns the value for key, and whether it was found and hasn't expired.
+	Get(key string) ([]byte, bool)
+
+	// Set stores value for key until ttl has passed. A ttl of zero never expires.
+	Set(key string, value []byte, ttl time.Duration)
+
+	Delete(key string)
+
+	// DeletePrefix deletes every key starting with prefix.
+	DeletePrefix(prefix string)
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func (e memoryCacheEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// memoryCacheSweepInterval is how often expired entries which are never read again are dropped.
+const memoryCacheSweepInterval = time.Minute
+
+// MemoryCache is an in-memory Cache, safe for concurrent use.
+type MemoryCache struct {
+	mu        sync.RWMutex
+	entries   map[string]memoryCacheEntry
+	nextSweep time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: map[string]memoryCacheEntry{},
+	}
+}
+
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	if entry.expired(time.Now()) {
+		m.mu.Lock()
+		// Unless it has been set again in the meantime.
+		if current, ok := m.entries[key]; ok && current.expires.Equal(entry.expires) {
+			delete(m.entries, key)
+		}
+		m.mu.Unlock()
+
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+func (m *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	now := time.Now()
+	entry := memoryCacheEntry{value: value}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Expired entries are dropped when read, and swept now and then so that those never read again don't pile up.
+	if now.After(m.nextSweep) {
+		for k, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+		m.nextSweep = now.Add(memoryCacheSweepInterval)
+	}
+
+	m.entries[key] = entry
+}
+
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+}
+
+func (m *MemoryCache) DeletePrefix(prefix string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(m.entries, k)
+		}
+	}
+}
+
+const cacheContextKey = "minimal.cache"
+
+// UseCache makes cache available to handlers through CacheFrom. Set up by the server using Config.Cache.
+func UseCache(cache Cache) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(cacheContextKey, cache)
+			return next(c)
+		}
+	}
+}
+
+// CacheFrom returns the cache of the server handling the request, or nil if there is none.
+func CacheFrom(c echo.Context) Cache {
+	cache, _ := c.Get(cacheContextKey).(Cache)
+	return cache
+}
+
+// captureWriter passes everything through to the underlying writer, keeping a copy of the body.
+type captureWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// cacheKeyPrefix is shared by every cache entry of the resource, so mutations can bust all of them.
+func (r *Resource[T]) cacheKeyPrefix() string {
+	return "minimal:resource:" + r.routePath() + ":"
+}
+
+// cacheHandler serves successful GET responses from the cache until they expire, and busts the cache of the
+// resource whenever a mutation succeeds. HEAD requests are neither cached nor bust the cache. Requests pass straight
+// through while caching isn't enabled.
+func (r *Resource[T]) cacheHandler(method string, h echo.HandlerFunc) echo.HandlerFunc {
+	if method == http.MethodHead {
+		return h
+	}
+
+	if method != http.MethodGet {
+		return func(c echo.Context) error {
+			err := h(c)
+			if cache := r.resolveCache(c); cache != nil && err == nil && c.Response().Status < http.StatusBadRequest {
+				cache.DeletePrefix(r.cacheKeyPrefix())
+			}
+
+			return err
+		}
+	}
+
+	return func(c echo.Context) error {
+		cache := r.resolveCache(c)
+		if cache == nil {
+			return h(c)
+		}
+
+		key := r.cacheKeyPrefix() + c.Request().URL.RequestURI()
+		if body, ok := cache.Get(key); ok {
+			return c.JSONBlob(http.StatusOK, body)
+		}
+
+		writer := &captureWriter{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = writer
+		defer func() {
+			c.Response().Writer = writer.ResponseWriter
+		}()
+
+		err := h(c)
+		if err == nil && c.Response().Status == http.StatusOK {
+			cache.Set(key, writer.body.Bytes(), r.cacheTTL)
+		}
+
+		return err
+	}
+}
+
+// readsPerRequest reports whether what list and get respond with may depend on who asks, in which case responses
+// can't be shared through the cache.
+func (r *Resource[T]) readsPerRequest() bool {
+	return r.scope != nil || r.canListAll != nil || r.canListById != nil || r.canViewDeleted != nil ||
+		r.fieldVisibility != nil || r.responseDecorator != nil || r.listTransformer != nil || r.getTransformer != nil
+}
+
+// checkCache disables caching on resources whose reads depend on the request, as one principal's cached response
+// would otherwise be served to another.
+func (r *Resource[T]) checkCache() {
+	if r.cacheTTL > 0 && r.readsPerRequest() {
+		log.Errorf("Caching is disabled for resource %s, as its responses depend on the request", r.displayName())
+		r.cacheTTL = 0
+	}
+}
+
+// resolveCache returns the cache set on the resource, falling back to the cache of the server.
+// Returns nil when caching isn't enabled.
+func (r *Resource[T]) resolveCache(c echo.Context) Cache {
+	if r.cacheTTL == 0 {
+		return nil
+	}
+
+	if r.cache != nil {
+		return r.cache
+	}
+
+	if cache := CacheFrom(c); cache != nil {
+		return cache
+	}
+
+	// Registered outside of a Server, so there is no shared cache to use.
+	return r.fallbackCache
+}
+
+/*
+EnableCache caches the list and get responses of the resource, keyed by path and query, for ttl. Any successful
+create, write or delete on the resource busts its cache. Responses are cached regardless of who asked, so this is
+meant for resources which respond the same to everyone, such as a rarely changing category list. Resources with a
+scope, read predicates, field visibility, a response decorator or transformers respond per request, so caching is
+refused for them when registering.
+Uses the cache of the server, see Config.Cache, unless one has been set using SetCache.
+*/
+func (r *Resource[T]) EnableCache(ttl time.Duration) {
+	r.cacheTTL = ttl
+	r.fallbackCache = NewMemoryCache()
+}
+
+// SetCache sets the cache backend used by EnableCache for this resource only.
+func (r *Resource[T]) SetCache(cache Cache) {
+	r.cache = cache
+}
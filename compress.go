@@ -0,0 +1,191 @@
+package minimal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultGzipContentTypes are compressed when GzipConfig.ContentTypes is empty. Types ending with a slash match the
+// whole family, e.g. "text/" matches "text/html".
+var DefaultGzipContentTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"image/svg+xml",
+	"text/",
+}
+
+type GzipConfig struct {
+	// Level is the gzip compression level. Defaults to gzip.DefaultCompression.
+	Level int
+
+	// MinLength is the response size in bytes below which responses are sent uncompressed.
+	MinLength int
+
+	// ContentTypes that are compressed. Defaults to DefaultGzipContentTypes.
+	ContentTypes []string
+}
+
+// Gzip compresses responses for clients accepting it, but only responses of the configured content types and of at
+// least the minimum length. Unlike echo's Gzip middleware, the response is held back until the decision can be made.
+func Gzip(config GzipConfig) echo.MiddlewareFunc {
+	if config.Level == 0 {
+		config.Level = gzip.DefaultCompression
+	}
+
+	// Checked up front, as an invalid level would otherwise fail every compressed response.
+	if _, err := gzip.NewWriterLevel(io.Discard, config.Level); err != nil {
+		log.Warnf("Invalid gzip level %d, using the default", config.Level)
+		config.Level = gzip.DefaultCompression
+	}
+
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = DefaultGzipContentTypes
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			resp := c.Response()
+			resp.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+
+			req := c.Request()
+			if req.Method == http.MethodHead || !strings.Contains(req.Header.Get(echo.HeaderAcceptEncoding), "gzip") {
+				return next(c)
+			}
+
+			writer := &gzipWriter{ResponseWriter: resp.Writer, config: config, status: http.StatusOK}
+			resp.Writer = writer
+			defer func() {
+				writer.close()
+				resp.Writer = writer.ResponseWriter
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+// gzipWriter buffers the start of the response until it is known whether it's worth compressing.
+type gzipWriter struct {
+	http.ResponseWriter
+	config GzipConfig
+
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	decided     bool
+	gz          *gzip.Writer
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf.Write(b)
+	if w.buf.Len() >= w.config.MinLength {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// decide sends the header, compressed or not, followed by what has been buffered so far.
+func (w *gzipWriter) decide() error {
+	w.decided = true
+
+	header := w.Header()
+	if w.buf.Len() > 0 && w.buf.Len() >= w.config.MinLength && header.Get(echo.HeaderContentEncoding) == "" &&
+		w.compressible(header.Get(echo.HeaderContentType)) {
+		header.Set(echo.HeaderContentEncoding, "gzip")
+		header.Del(echo.HeaderContentLength)
+
+		gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.config.Level)
+		if err != nil {
+			return err
+		}
+		w.gz = gz
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+
+	w.buf.Reset()
+	return err
+}
+
+// close finishes the response. If the handler wrote nothing at all, no header is sent, leaving the response to the
+// error handler once the original writer has been restored.
+func (w *gzipWriter) close() {
+	if !w.decided {
+		if w.buf.Len() == 0 && !w.wroteHeader {
+			return
+		}
+		_ = w.decide()
+	}
+
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
+
+func (w *gzipWriter) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return matchesContentType(mediaType, w.config.ContentTypes)
+}
+
+// matchesContentType reports whether mediaType is one of types, where types ending with a slash match the whole family.
+func matchesContentType(mediaType string, types []string) bool {
+	for _, t := range types {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+
+	return false
+}
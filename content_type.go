@@ -0,0 +1,43 @@
+package minimal
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"mime"
+	"net/http"
+)
+
+var (
+	ErrorUnsupportedMediaType = errors.New("unsupported content type")
+)
+
+/*
+RequireContentType rejects writes (POST, PUT and PATCH) with 415 unless their Content-Type is one of types, which
+defaults to application/json. Other methods pass through, so it can be added to a whole resource:
+
+	api.Middlewares(minimal.RequireContentType())
+
+This prevents empty records from being created when a client forgets the header and nothing gets bound.
+*/
+func RequireContentType(types ...string) echo.MiddlewareFunc {
+	if len(types) == 0 {
+		types = []string{echo.MIMEApplicationJSON}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			switch c.Request().Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch:
+			default:
+				return next(c)
+			}
+
+			mediaType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+			if err != nil || !contains(types, mediaType) {
+				return failCode(c, http.StatusUnsupportedMediaType, ErrorUnsupportedMediaType)
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// CORSConfig allows browser clients on other origins to call the server. CORS is disabled while AllowOrigins is empty.
+type CORSConfig struct {
+	// AllowOrigins are the origins allowed to make requests, e.g. "https://example.com". A * matches anything, such
+	// as in "https://*.example.com", and "*" on its own allows every origin.
+	AllowOrigins []string
+
+	// AllowMethods are the methods allowed in requests. Defaults to the methods of the requested route.
+	AllowMethods []string
+
+	// AllowHeaders are the request headers clients may send, besides the CORS-safelisted ones.
+	AllowHeaders []string
+
+	// ExposeHeaders are the response headers clients may read, besides the CORS-safelisted ones, e.g. HeaderTotalCount.
+	ExposeHeaders []string
+
+	// AllowCredentials lets clients send cookies and authorization headers. Can't be combined with the "*" origin.
+	AllowCredentials bool
+}
+
+// DevelopmentCORS allows frontends served from localhost, on any port, as is common during development.
+var DevelopmentCORS = CORSConfig{
+	AllowOrigins: []string{
+		"http://localhost",
+		"http://localhost:*",
+		"http://127.0.0.1",
+		"http://127.0.0.1:*",
+	},
+	ExposeHeaders:    []string{HeaderTotalCount},
+	AllowCredentials: true,
+}
+
+func (c CORSConfig) enabled() bool {
+	return len(c.AllowOrigins) > 0
+}
+
+// CORS returns the middleware answering preflight requests and adding the CORS headers to responses.
+func CORS(config CORSConfig) echo.MiddlewareFunc {
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins:     config.AllowOrigins,
+		AllowMethods:     config.AllowMethods,
+		AllowHeaders:     config.AllowHeaders,
+		ExposeHeaders:    config.ExposeHeaders,
+		AllowCredentials: config.AllowCredentials,
+	})
+}
@@ -0,0 +1,115 @@
+package minimal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strconv"
+)
+
+// Page size of cursor pagination when SetPagination hasn't set one.
+const defaultCursorPerPage = 100
+
+// CursorResponse is the response to listing a resource paginated by cursor. NextCursor is passed as ?after= to get
+// the following page, and is empty on the last page.
+type CursorResponse struct {
+	Items      any
+	NextCursor string `json:",omitempty"`
+}
+
+// listAfter runs the list query for the page following the cursor in ?after=, or the first page without one. The
+// entities are ordered by the cursor column, so rows inserted or deleted meanwhile don't shift the pages.
+func (r *Resource[T]) listAfter(c echo.Context, q *gorm.DB) (*CursorResponse, []T, error) {
+	if c.QueryParam("sort") != "" {
+		return nil, nil, fmt.Errorf("%w: can't sort when paginating by cursor", ErrorInvalidData)
+	}
+
+	field, err := r.cursorSchemaField(q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+	if after := c.QueryParam("after"); after != "" {
+		value, err := decodeCursor(after, field.FieldType)
+		if err != nil {
+			return nil, nil, err
+		}
+		q = q.Where(clause.Gt{Column: column, Value: value})
+	}
+
+	perPage := defaultCursorPerPage
+	if r.maxPerPage > 0 {
+		_, perPage = r.pageParams(c)
+	}
+
+	// Pages longer than the list size cap would be truncated after the next cursor was taken, skipping the rest.
+	if limit := maxListSize(c); limit > 0 && perPage > limit {
+		perPage = limit
+	}
+
+	// One more than the page is loaded, to tell whether there is a next page.
+	result, err := r.listAllQuery(c, q.Order(clause.OrderByColumn{Column: column}).Limit(perPage+1))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response := &CursorResponse{}
+	if len(result) > perPage {
+		result = result[:perPage]
+
+		last, _ := field.ValueOf(reflect.ValueOf(&result[perPage-1]).Elem())
+		if response.NextCursor, err = encodeCursor(last); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return response, result, nil
+}
+
+// cursorSchemaField looks the cursor field up on T, by column or field name.
+func (r *Resource[T]) cursorSchemaField(q *gorm.DB) (*schema.Field, error) {
+	stmt := &gorm.Statement{DB: q}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+
+	field := stmt.Schema.LookUpField(r.cursorField)
+	if field == nil || field.DBName == "" {
+		return nil, fmt.Errorf("unknown cursor field %s", r.cursorField)
+	}
+
+	return field, nil
+}
+
+// encodeCursor makes the value of the cursor field opaque to clients, so they don't come to depend on it.
+func encodeCursor(value any) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor decodes a cursor into a value of the cursor field's type.
+func decodeCursor(cursor string, t reflect.Type) (any, error) {
+	invalid := fmt.Errorf("%w: invalid cursor %s", ErrorInvalidData, strconv.Quote(cursor))
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+
+	value := reflect.New(t)
+	if err := json.Unmarshal(b, value.Interface()); err != nil {
+		return nil, invalid
+	}
+
+	return value.Elem().Interface(), nil
+}
@@ -1,27 +1,153 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"github.com/labstack/gommon/log"
 	"golang.org/x/tools/go/packages"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
 	stdLog "log"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const loadMode = packages.NeedName | packages.NeedTypes
 
 var (
+	// Db is the global database, set up by the Init functions.
+	//
+	// Deprecated: use Server.DB, or Resource.SetDB for resources living in other databases. Db is only kept as the
+	// fallback for code that doesn't have a handle of its own.
 	Db            *gorm.DB
 	IsInitialized bool
+
+	// Set by the health check loop when pings have been failing.
+	unhealthy int32
+
+	// Used to give every in-memory database a unique name.
+	inMemoryCount int32
+
+	// Decides table and column names, gorm's default naming strategy when nil.
+	namer schema.Namer
+
+	// Tracks the model types that have been migrated, so models aren't migrated twice.
+	migrateMu   sync.Mutex
+	autoMigrate = true
+	migrated    = map[migration]bool{}
 )
 
+// A model type migrated on a database.
+type migration struct {
+	db *gorm.DB
+	t  reflect.Type
+}
+
+// Dialect selects the database driver.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+)
+
+// InitDatabase sets the database up against a Postgres database.
 func InitDatabase(dsn string) (*gorm.DB, error) {
-	// Shut the postgres logging up.
+	return open(postgres.Open(dsn))
+}
+
+// InitDialect sets the database up using the driver of dialect. An empty dialect is detected from the DSN, where
+// MySQL DSNs look like "user:pass@tcp(host:3306)/db", SQLite DSNs are file names or "file:" URIs, and anything else is
+// taken to be Postgres.
+func InitDialect(dialect Dialect, dsn string) (*gorm.DB, error) {
+	d, err := dialector(dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(d)
+}
+
+/*
+UseReplicas routes the reads of db to the read replicas at dsns, while writes, and everything run in a transaction,
+stay on the primary. A replica is picked at random for every query. The dialect is detected from each DSN when empty:
+
+	db, err := database.InitDialect(database.Postgres, primary)
+	err = database.UseReplicas(db, database.Postgres, []string{replica1, replica2})
+
+Reads which have to see a write straight away, despite replication lag, can be sent to the primary using
+db.Clauses(dbresolver.Write).
+*/
+func UseReplicas(db *gorm.DB, dialect Dialect, dsns []string) error {
+	replicas := make([]gorm.Dialector, 0, len(dsns))
+	for _, dsn := range dsns {
+		d, err := dialector(dialect, dsn)
+		if err != nil {
+			return err
+		}
+		replicas = append(replicas, d)
+	}
+
+	return db.Use(dbresolver.Register(dbresolver.Config{Replicas: replicas}))
+}
+
+// dialector returns the driver of dialect connecting to dsn, detecting the dialect from the DSN when empty.
+func dialector(dialect Dialect, dsn string) (gorm.Dialector, error) {
+	if dialect == "" {
+		dialect = DetectDialect(dsn)
+	}
+
+	switch dialect {
+	case Postgres:
+		return postgres.Open(dsn), nil
+	case MySQL:
+		return mysql.Open(strings.TrimPrefix(dsn, "mysql://")), nil
+	case SQLite:
+		return sqlite.Open(dsn), nil
+	}
+
+	return nil, fmt.Errorf("unsupported dialect %s", dialect)
+}
+
+// DetectDialect guesses the dialect of a DSN, defaulting to Postgres.
+func DetectDialect(dsn string) Dialect {
+	switch {
+	case strings.HasPrefix(dsn, "mysql://") || strings.Contains(dsn, "@tcp(") || strings.Contains(dsn, "@unix("):
+		return MySQL
+	case strings.HasPrefix(dsn, "file:") || dsn == ":memory:" || strings.HasSuffix(dsn, ".db") ||
+		strings.HasSuffix(dsn, ".sqlite") || strings.HasSuffix(dsn, ".sqlite3"):
+		return SQLite
+	}
+
+	return Postgres
+}
+
+// SetNamer sets the naming strategy used for tables and columns by databases initialized afterwards.
+// Useful for existing databases which don't follow gorm's snake_case defaults.
+func SetNamer(n schema.Namer) {
+	namer = n
+}
+
+// InitInMemory sets the database up against a fresh in-memory SQLite database, which is handy for tests.
+func InitInMemory() (*gorm.DB, error) {
+	n := atomic.AddInt32(&inMemoryCount, 1)
+
+	// Every connection to ":memory:" gets its own database, so name it and share the cache between the pool instead.
+	return open(sqlite.Open(fmt.Sprintf("file:minimal%d?mode=memory&cache=shared", n)))
+}
+
+func open(dialector gorm.Dialector) (*gorm.DB, error) {
+	// Shut the database logging up.
 	silentLogger := gormLogger.New(
 		stdLog.New(os.Stdout, "\r\n", stdLog.LstdFlags), // io writer
 		gormLogger.Config{
@@ -32,32 +158,164 @@ func InitDatabase(dsn string) (*gorm.DB, error) {
 	)
 
 	// Open a connection with the database, otherwise quit the main process.
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: silentLogger,
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         silentLogger,
+		NamingStrategy: namer,
 	})
 
 	// Update the global DbHandle instance.
+	use(db)
+	IsInitialized = err == nil
+
+	// Finally, return the instance of the db we created.
+	return Db, err
+}
+
+// UseDB sets the database up with an already configured handle, e.g. one with plugins or a custom dialector, instead
+// of opening one. The naming strategy set by SetNamer doesn't apply, as it is part of the handle's configuration.
+func UseDB(db *gorm.DB) {
+	use(db)
+	IsInitialized = db != nil
+}
+
+func use(db *gorm.DB) {
 	Db = db
 
-	if err == nil {
-		IsInitialized = true
+	// A new database hasn't had anything migrated yet.
+	migrateMu.Lock()
+	migrated = map[migration]bool{}
+	migrateMu.Unlock()
+}
+
+// Ready reports whether the database has been initialized and can be queried.
+func Ready() bool {
+	return IsInitialized && Db != nil && atomic.LoadInt32(&unhealthy) == 0
+}
+
+// SetConnMaxIdleTime sets how long a connection may sit idle in the pool before it is closed.
+// Useful when idle connections get killed by the server or a firewall.
+func SetConnMaxIdleTime(d time.Duration) error {
+	sqlDb, err := Db.DB()
+	if err != nil {
+		return err
 	}
 
-	// Finally, return the instance of the db we created.
-	return Db, err
+	sqlDb.SetConnMaxIdleTime(d)
+	return nil
+}
+
+// StartHealthCheck pings db every interval in the background, which keeps the pool healthy.
+// After maxFailures consecutive failed pings the database is reported as not ready, until a ping succeeds again.
+// Calling the returned function stops the loop, calling it again does nothing.
+func StartHealthCheck(db *gorm.DB, interval time.Duration, maxFailures int) func() {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		failures := 0
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			if err := Ping(context.Background(), db); err != nil {
+				failures++
+				log.Warn("Database ping failed: ", err)
+
+				if failures >= maxFailures && atomic.SwapInt32(&unhealthy, 1) == 0 {
+					log.Error(fmt.Sprintf("Database ping failed %d times in a row, marking it as not ready", failures))
+				}
+				continue
+			}
+
+			failures = 0
+			if atomic.SwapInt32(&unhealthy, 0) == 1 {
+				log.Info("Database ping succeeded, marking it as ready")
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}
+}
+
+// Ping checks that a connection to db can be made.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDb, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDb.PingContext(ctx)
+}
+
+// SetAutoMigrate turns AutoMigrate on or off, for when migrations are run as a separate step.
+func SetAutoMigrate(enabled bool) {
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+
+	autoMigrate = enabled
+}
+
+// Migratable is implemented by models needing more schema than gorm infers, such as partial indexes or check
+// constraints. PostMigrate is run by AutoMigrate right after the model has been migrated.
+type Migratable interface {
+	PostMigrate(db *gorm.DB) error
 }
 
 // AutoMigrate Automatically migrates a gorm.Model interface.
-// This simply calls AutoMigrate on the model argument.
-// Additional logging.
-func AutoMigrate(model interface{}) {
-	err := Db.AutoMigrate(model)
+// This simply calls AutoMigrate on the model argument, followed by PostMigrate if the model is Migratable.
+// Additional logging. Every model type is only migrated once per database, and nothing happens when disabled.
+func AutoMigrate(model interface{}) error {
+	return AutoMigrateOn(Db, model)
+}
+
+// AutoMigrateOn is like AutoMigrate, but migrates the model on db rather than the global database.
+func AutoMigrateOn(db *gorm.DB, model interface{}) error {
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+
+	if !autoMigrate {
+		return nil
+	}
+
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	key := migration{db: db, t: t}
+	if migrated[key] {
+		return nil
+	}
+
+	// Schema lookups would otherwise be answered by a read replica, which is migrated by replication, not by us.
+	db = db.Clauses(dbresolver.Write)
+	err := db.AutoMigrate(model)
 
 	if err != nil {
 		log.Error(fmt.Sprintf("Unable to migrate model %s", reflect.TypeOf(model)))
 		log.Error(err.Error())
-		return
+		return err
+	}
+
+	if m, ok := model.(Migratable); ok {
+		if err := m.PostMigrate(db); err != nil {
+			log.Error(fmt.Sprintf("Unable to run post migration of model %s", reflect.TypeOf(model)))
+			log.Error(err.Error())
+			return err
+		}
 	}
 
+	migrated[key] = true
 	log.Info(fmt.Sprintf("Migrated model of type %s", reflect.TypeOf(model)))
+	return nil
 }
@@ -0,0 +1,36 @@
+package minimal
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+/*
+Deprecated marks every response as deprecated with the Deprecation header, signaling clients to migrate. When sunset
+isn't zero it is announced in the Sunset header, and when successor isn't empty it is linked as the successor version.
+Use it on an echo group to deprecate all of a provider's routes:
+
+	v1 := e.Group("/v1", minimal.Deprecated(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), "/v2"))
+
+Resources can be deprecated using Resource.Deprecate.
+*/
+func Deprecated(sunset time.Time, successor string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set("Deprecation", "true")
+
+			if !sunset.IsZero() {
+				header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+
+			if successor != "" {
+				header.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			}
+
+			return next(c)
+		}
+	}
+}
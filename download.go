@@ -0,0 +1,77 @@
+package minimal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// download serves a single field of an entity as a file, so that range requests and conditional requests work.
+func (r *Resource[T]) download(c echo.Context) error {
+	field := c.Param("field")
+	if !contains(r.downloadFields, field) {
+		return r.fail(c, OperationDownload, http.StatusNotFound, ErrorNoResourceFound)
+	}
+
+	id, err := r.parseID(c)
+	if err != nil {
+		return r.fail(c, OperationDownload, http.StatusBadRequest, err)
+	}
+
+	q, err := r.readQuery(c)
+	if err != nil {
+		return r.fail(c, OperationDownload, http.StatusForbidden, err)
+	}
+
+	entity, err := r.listByIdQuery(c, q, id)
+	if err != nil {
+		if errors.Is(err, ErrorNoResourceFound) {
+			return r.fail(c, OperationDownload, http.StatusNotFound, ErrorNoResourceFound)
+		}
+
+		if errors.Is(err, ErrorNoResourceAccess) {
+			return r.fail(c, OperationDownload, http.StatusForbidden, ErrorNoResourceAccess)
+		}
+
+		log.Errorf("Could not download for resource %s: %s", reflect.TypeOf(r), err)
+		return r.fail(c, OperationDownload, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	content, modTime, err := fieldContent(entity, field)
+	if err != nil {
+		log.Errorf("Could not download for resource %s: %s", reflect.TypeOf(r), err)
+		return r.fail(c, OperationDownload, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	c.Response().Header().Set("ETag", fmt.Sprintf(`"%x"`, sha256.Sum256(content)))
+	http.ServeContent(c.Response(), c.Request(), field, modTime, bytes.NewReader(content))
+	return nil
+}
+
+// fieldContent returns the named string or byte slice field of entity, along with its UpdatedAt field if it has one.
+func fieldContent(entity any, name string) ([]byte, time.Time, error) {
+	v := reflect.Indirect(reflect.ValueOf(entity))
+
+	var modTime time.Time
+	if updated := v.FieldByName("UpdatedAt"); updated.IsValid() {
+		modTime, _ = updated.Interface().(time.Time)
+	}
+
+	field := v.FieldByName(name)
+	switch {
+	case !field.IsValid():
+		return nil, modTime, fmt.Errorf("no field %s", name)
+	case field.Kind() == reflect.String:
+		return []byte(field.String()), modTime, nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+		return field.Bytes(), modTime, nil
+	}
+
+	return nil, modTime, fmt.Errorf("field %s must be a string or byte slice", name)
+}
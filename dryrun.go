@@ -0,0 +1,32 @@
+package minimal
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// Returned from the dry run transaction to roll it back.
+var errDryRun = errors.New("dry run")
+
+// isDryRun reports whether the client asked to only validate, using ?dry_run=true or the X-Dry-Run header.
+func isDryRun(c echo.Context) bool {
+	return c.QueryParam("dry_run") == "true" || c.Request().Header.Get("X-Dry-Run") == "true"
+}
+
+// dryRun runs f in a transaction on q which is always rolled back, so nothing f writes is persisted.
+func dryRun(q *gorm.DB, f func(tx *gorm.DB) error) error {
+	err := q.Transaction(func(tx *gorm.DB) error {
+		if err := f(tx); err != nil {
+			return err
+		}
+
+		return errDryRun
+	})
+
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+
+	return err
+}
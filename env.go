@@ -0,0 +1,136 @@
+package minimal
+
+import (
+	"fmt"
+	"github.com/kaiaverkvist/minimal/database"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envVar binds an environment variable to a field of Config.
+type envVar struct {
+	name string
+	set  func(config *Config, value string) error
+}
+
+// The environment variables read by MergeEnv.
+var envVars = []envVar{
+	{"DSN", envString(func(c *Config) *string { return &c.DSN })},
+	{"DIALECT", func(c *Config, v string) error {
+		c.Dialect = database.Dialect(v)
+		return nil
+	}},
+	{"REPLICAS", envList(func(c *Config) *[]string { return &c.Replicas })},
+	{"HTTP_PORT", func(c *Config, v string) error {
+		port, err := strconv.ParseUint(v, 10, 16)
+		c.HttpPort = uint(port)
+		return err
+	}},
+	{"AUTO_TLS", envBool(func(c *Config) *bool { return &c.AutoTLS })},
+	{"CERT_KEY_PATH", envString(func(c *Config) *string { return &c.CertKeyPath })},
+	{"CERT_PRIVATE_KEY_PATH", envString(func(c *Config) *string { return &c.CertPrivateKeyPath })},
+	{"DOMAINS", envList(func(c *Config) *[]string { return &c.Domains })},
+	{"READ_TIMEOUT", envDuration(func(c *Config) *time.Duration { return &c.ReadTimeout })},
+	{"READ_HEADER_TIMEOUT", envDuration(func(c *Config) *time.Duration { return &c.ReadHeaderTimeout })},
+	{"WRITE_TIMEOUT", envDuration(func(c *Config) *time.Duration { return &c.WriteTimeout })},
+	{"IDLE_TIMEOUT", envDuration(func(c *Config) *time.Duration { return &c.IdleTimeout })},
+	{"MAX_HEADER_BYTES", envInt(func(c *Config) *int { return &c.MaxHeaderBytes })},
+	{"SHUTDOWN_TIMEOUT", envDuration(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"REQUEST_TIMEOUT", envDuration(func(c *Config) *time.Duration { return &c.RequestTimeout })},
+	{"FRIENDLY_LOGGING", envBool(func(c *Config) *bool { return &c.FriendlyLogging })},
+	{"EXTERNAL_BASE_URL", envString(func(c *Config) *string { return &c.ExternalBaseURL })},
+	{"API_PREFIX", envString(func(c *Config) *string { return &c.APIPrefix })},
+	{"MAX_LIST_SIZE", envInt(func(c *Config) *int { return &c.MaxListSize })},
+	{"TABLE_PREFIX", envString(func(c *Config) *string { return &c.TablePrefix })},
+	{"DISABLE_AUTO_MIGRATE", envBool(func(c *Config) *bool { return &c.DisableAutoMigrate })},
+	{"ENABLE_GZIP", envBool(func(c *Config) *bool { return &c.EnableGzip })},
+	{"GZIP_LEVEL", envInt(func(c *Config) *int { return &c.GzipLevel })},
+	{"MINIFY", envBool(func(c *Config) *bool { return &c.Minify.Enabled })},
+	{"MINIFY_EXCLUDE_CONTENT_TYPES", envList(func(c *Config) *[]string { return &c.Minify.ExcludeContentTypes })},
+	{"CORS_ALLOW_ORIGINS", envList(func(c *Config) *[]string { return &c.CORS.AllowOrigins })},
+	{"RATE_LIMIT_RPS", func(c *Config, v string) error {
+		rps, err := strconv.ParseFloat(v, 64)
+		c.RateLimit.RequestsPerSecond = rps
+		return err
+	}},
+	{"RATE_LIMIT_BURST", envInt(func(c *Config) *int { return &c.RateLimit.Burst })},
+}
+
+/*
+ConfigFromEnv reads the configuration from environment variables, for 12-factor deploys. Unset variables are left at
+their defaults, which is port 80 with JSON logging and nothing else enabled. See MergeEnv for the variables read.
+*/
+func ConfigFromEnv() (Config, error) {
+	return MergeEnv(Config{HttpPort: 80})
+}
+
+/*
+MergeEnv overrides config with the environment variables which are set, so a config set up in code can be adjusted
+per deployment:
+
+	config, err := minimal.MergeEnv(minimal.Config{HttpPort: 8080, FriendlyLogging: true})
+
+The variables are DSN, DIALECT, REPLICAS, HTTP_PORT, AUTO_TLS, CERT_KEY_PATH, CERT_PRIVATE_KEY_PATH, DOMAINS,
+READ_TIMEOUT, READ_HEADER_TIMEOUT, WRITE_TIMEOUT, IDLE_TIMEOUT, MAX_HEADER_BYTES, SHUTDOWN_TIMEOUT, REQUEST_TIMEOUT,
+FRIENDLY_LOGGING, EXTERNAL_BASE_URL, API_PREFIX, MAX_LIST_SIZE, TABLE_PREFIX, DISABLE_AUTO_MIGRATE, ENABLE_GZIP,
+GZIP_LEVEL, MINIFY, MINIFY_EXCLUDE_CONTENT_TYPES, CORS_ALLOW_ORIGINS, RATE_LIMIT_RPS and RATE_LIMIT_BURST. Lists are
+comma separated, and durations are written like "30s". A value which can't be parsed is an error naming the variable.
+*/
+func MergeEnv(config Config) (Config, error) {
+	for _, v := range envVars {
+		value, ok := os.LookupEnv(v.name)
+		if !ok {
+			continue
+		}
+
+		if err := v.set(&config, strings.TrimSpace(value)); err != nil {
+			return config, fmt.Errorf("invalid %s: %w", v.name, err)
+		}
+	}
+
+	return config, nil
+}
+
+func envString(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func envBool(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) (err error) {
+		*field(c), err = strconv.ParseBool(v)
+		return err
+	}
+}
+
+func envInt(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) (err error) {
+		*field(c), err = strconv.Atoi(v)
+		return err
+	}
+}
+
+func envDuration(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) (err error) {
+		*field(c), err = time.ParseDuration(v)
+		return err
+	}
+}
+
+func envList(field func(c *Config) *[]string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+
+		*field(c) = list
+		return nil
+	}
+}
@@ -0,0 +1,136 @@
+package minimal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kaiaverkvist/minimal/res"
+	"github.com/labstack/echo/v4"
+	"sort"
+	"strings"
+)
+
+// Operations passed to the OnError hook, telling which handler failed.
+const (
+	OperationListAll     = "list_all"
+	OperationCountAll    = "count_all"
+	OperationGetById     = "get_by_id"
+	OperationWriteById   = "write_by_id"
+	OperationCreate      = "create"
+	OperationDeleteById  = "delete_by_id"
+	OperationRestoreById = "restore_by_id"
+	OperationBulkWrite   = "bulk_write"
+	OperationBulkCreate  = "bulk_create"
+	OperationExport      = "export"
+	OperationDownload    = "download"
+)
+
+// errorCodes are the machine-readable codes of the sentinel errors, sent along with the message in error responses.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrorNoResourceAccess, "no_resource_access"},
+	{ErrorNoResourceFound, "no_resource_found"},
+	{ErrorDatabase, "database"},
+	{ErrorNoBindType, "no_bind_type"},
+	{ErrorInvalidID, "invalid_id"},
+	{ErrorNotReady, "not_ready"},
+	{ErrorAsyncCreate, "async_create"},
+	{ErrorNoFilter, "no_filter"},
+	{ErrorConflict, "conflict"},
+	{ErrorInvalidTenant, "invalid_tenant"},
+	{ErrorNoTenantAccess, "no_tenant_access"},
+	{ErrorUnsupportedMediaType, "unsupported_media_type"},
+	{ErrorRateLimited, "rate_limited"},
+	{ErrorUnauthorized, "unauthorized"},
+	// Last, as more specific errors may wrap it.
+	{ErrorInvalidData, "invalid_data"},
+}
+
+// errorCode returns the code of the sentinel error err wraps, or an empty string when it wraps none of them.
+func errorCode(err error) string {
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+
+	return ""
+}
+
+// failCode responds with err and its code, for middlewares which can't go through Resource.fail.
+func failCode(c echo.Context, status int, err error) error {
+	return res.FailCodeWithCode(c, status, errorCode(err), err)
+}
+
+// fail translates err into an error response. The OnError hook gets the first say, and the default mapping is only
+// used when it returns nil without having written a response itself.
+func (r *Resource[T]) fail(c echo.Context, op string, code int, err error) error {
+	if r.onError != nil {
+		if herr := r.onError(c, op, err); herr != nil {
+			return herr
+		}
+
+		if c.Response().Committed {
+			return nil
+		}
+	}
+
+	err = res.WithCode(errorCode(err), err)
+
+	// Keep per-field details structured, rather than flattening them into the message.
+	var fieldErr FieldErrorer
+	if errors.As(err, &fieldErr) {
+		return res.FailData(c, code, err, fieldErr.FieldErrors())
+	}
+
+	return res.FailCode(c, code, err)
+}
+
+// FieldErrorer is implemented by errors carrying a message per invalid field, such as FieldErrors. Handlers respond
+// to them with the messages keyed by field in the Data of the response.
+type FieldErrorer interface {
+	FieldErrors() map[string]string
+}
+
+// FieldErrors maps field names to what is wrong with them, e.g. for a create transformer to return:
+//
+//	return nil, minimal.FieldErrors{"Email": "is already taken"}
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for i, field := range fields {
+		fields[i] = field + " " + e[field]
+	}
+
+	return fmt.Sprintf("%s: %s", ErrorInvalidData, strings.Join(fields, ", "))
+}
+
+func (e FieldErrors) FieldErrors() map[string]string {
+	return e
+}
+
+func (e FieldErrors) Unwrap() error {
+	return ErrorInvalidData
+}
+
+/*
+OnError sets a hook which is invoked whenever a handler is about to respond with an error, e.g. to respond with
+problem+json instead:
+
+	api.OnError(func(c echo.Context, op string, err error) error {
+		return c.JSON(http.StatusBadRequest, Problem{Title: err.Error()})
+	})
+
+The hook may either write the response itself or return an error for echo to handle. Returning nil without writing
+a response falls back to the default mapping.
+*/
+func (r *Resource[T]) OnError(hook func(c echo.Context, op string, err error) error) {
+	r.onError = hook
+}
@@ -0,0 +1,120 @@
+package minimal
+
+import (
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+	"net/http"
+	"reflect"
+)
+
+// MIMEApplicationNDJSON is the content type of exports, one JSON encoded entity per line.
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+// export streams every entity getAll would list as newline delimited JSON, loading them in batches so that memory
+// use stays bounded regardless of the table size. Entities are mapped by the list transformer, or otherwise the get
+// transformer, like the responses of the other routes.
+func (r *Resource[T]) export(c echo.Context) error {
+	if r.canListAll != nil {
+		if !r.canListAll(c) {
+			return r.fail(c, OperationExport, http.StatusForbidden, ErrorNoResourceAccess)
+		}
+	}
+
+	q, err := r.readQuery(c)
+	if err != nil {
+		return r.fail(c, OperationExport, http.StatusForbidden, err)
+	}
+
+	q, err = r.updatedSince(c, r.searched(c, r.filtered(c, q)))
+	if err != nil {
+		return r.fail(c, OperationExport, http.StatusBadRequest, err)
+	}
+
+	resp := c.Response()
+	encoder := json.NewEncoder(resp)
+
+	// Transformer errors are told apart from database errors, as they are responded with differently.
+	var transformErr error
+
+	var batch []T
+	tx := applyPreloads(q, r.preloads).FindInBatches(&batch, r.exportBatchSize, func(tx *gorm.DB, n int) error {
+		lines, err := r.exportLines(c, batch)
+		if err != nil {
+			transformErr = err
+			return err
+		}
+
+		if !resp.Committed {
+			resp.Header().Set(echo.HeaderContentType, MIMEApplicationNDJSON)
+			resp.WriteHeader(http.StatusOK)
+		}
+
+		for _, line := range lines {
+			if err := encoder.Encode(line); err != nil {
+				return err
+			}
+		}
+
+		resp.Flush()
+		return nil
+	})
+
+	if tx.Error != nil {
+		// Once streaming has started the status can't be changed anymore, so the export just ends early.
+		if resp.Committed {
+			log.Errorf("Could not export resource %s: %s", reflect.TypeOf(r), tx.Error)
+			return nil
+		}
+
+		if transformErr != nil {
+			return r.failTransform(c, OperationExport, transformErr)
+		}
+
+		log.Errorf("Could not export resource %s: %s", reflect.TypeOf(r), tx.Error)
+
+		return r.fail(c, OperationExport, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	// Nothing to export.
+	if !resp.Committed {
+		resp.Header().Set(echo.HeaderContentType, MIMEApplicationNDJSON)
+		resp.WriteHeader(http.StatusOK)
+	}
+
+	return nil
+}
+
+// exportLines maps a batch of entities to the values exported, one per line. What the list transformer responds with is
+// exported element by element when it is a slice, and as a single line otherwise.
+func (r *Resource[T]) exportLines(c echo.Context, batch []T) ([]any, error) {
+	if r.listTransformer != nil {
+		items, err := r.listTransformer(c, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		v := reflect.ValueOf(items)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return []any{items}, nil
+		}
+
+		lines := make([]any, v.Len())
+		for i := range lines {
+			lines[i] = v.Index(i).Interface()
+		}
+		return lines, nil
+	}
+
+	lines := make([]any, len(batch))
+	for i := range batch {
+		item, err := transformed(r, c, &batch[i], r.getTransformer)
+		if err != nil {
+			return nil, err
+		}
+		lines[i] = item
+	}
+
+	return lines, nil
+}
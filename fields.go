@@ -0,0 +1,296 @@
+package minimal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const presentFieldsContextKey = "minimal.presentFields"
+
+// bindTracked binds the request onto bound like c.Bind does, and additionally records which fields of bound were
+// actually sent by the client. The field names can be read back using presentFields.
+func bindTracked(c echo.Context, bound any) error {
+	req := c.Request()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if err := c.Bind(bound); err != nil {
+		return err
+	}
+
+	var keys []string
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(body, &raw); err == nil {
+			for key := range raw {
+				keys = append(keys, key)
+			}
+		}
+	} else if params, err := c.FormParams(); err == nil {
+		for key := range params {
+			keys = append(keys, key)
+		}
+	}
+
+	c.Set(presentFieldsContextKey, matchFields(reflect.TypeOf(bound), keys))
+	return nil
+}
+
+// presentFields returns the field names recorded by bindTracked, and whether anything was recorded at all.
+func presentFields(c echo.Context) ([]string, bool) {
+	fields, ok := c.Get(presentFieldsContextKey).([]string)
+	return fields, ok
+}
+
+// matchFields maps request keys onto the exported field names of t, matching the same way encoding/json does.
+func matchFields(t reflect.Type, keys []string) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	fields := []string{}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, hasTagName, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && !hasTagName {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, matchFields(ft, keys)...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		for _, key := range keys {
+			if key == name || (!hasTagName && strings.EqualFold(key, name)) {
+				fields = append(fields, field.Name)
+				break
+			}
+		}
+	}
+
+	return fields
+}
+
+// mergeStruct copies the non-zero exported fields of patch onto the fields of target with the same name, dereferencing
+// pointers in patch and flattening embedded structs. A field whose type can't be merged fails with an error naming it,
+// unless ignoreMismatch is set, in which case it is logged and skipped.
+func mergeStruct(target any, patch any, ignoreMismatch bool) error {
+	dst := reflect.Indirect(reflect.ValueOf(target))
+	src := reflect.ValueOf(patch)
+	for src.Kind() == reflect.Pointer {
+		if src.IsNil() {
+			return nil
+		}
+		src = src.Elem()
+	}
+
+	if src.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: can't merge %s", ErrorInvalidData, src.Type())
+	}
+
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Type().Field(i)
+		value := src.Field(i)
+
+		if field.Anonymous && reflect.Indirect(value).Kind() == reflect.Struct {
+			if value.Kind() == reflect.Pointer && value.IsNil() {
+				continue
+			}
+			if err := mergeStruct(target, value.Interface(), ignoreMismatch); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !field.IsExported() || value.IsZero() {
+			continue
+		}
+
+		dstField := dst.FieldByName(field.Name)
+		if !dstField.IsValid() || !dstField.CanSet() {
+			continue
+		}
+
+		if err := setField(dstField, value, field.Name); err != nil {
+			if ignoreMismatch {
+				log.Warn("Skipping field while patching: ", err)
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setField assigns value to dst, dereferencing value or converting between types of the same kind where needed.
+func setField(dst reflect.Value, value reflect.Value, name string) error {
+	for !value.Type().AssignableTo(dst.Type()) && value.Kind() == reflect.Pointer && dst.Kind() != reflect.Pointer {
+		if value.IsNil() {
+			value = reflect.Zero(value.Type().Elem())
+			continue
+		}
+		value = value.Elem()
+	}
+
+	switch {
+	case value.Type().AssignableTo(dst.Type()):
+		dst.Set(value)
+	case value.Kind() == dst.Kind() && value.Type().ConvertibleTo(dst.Type()):
+		dst.Set(value.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("%w: field %s can't be merged, %s into %s", ErrorInvalidData, name, value.Type(), dst.Type())
+	}
+
+	return nil
+}
+
+// allFields returns the names of every field of t that can be bound, as matchFields would for a request sending all
+// of them.
+func allFields(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	fields := []string{}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		_, hasTagName, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && !hasTagName {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, allFields(ft)...)
+				continue
+			}
+		}
+
+		if field.IsExported() {
+			fields = append(fields, field.Name)
+		}
+	}
+
+	return fields
+}
+
+// applyFields copies the named fields from patch onto target, including zero values, and returns them as a map
+// suitable for gorm's Updates. Nil pointers in patch are written as zero values. Fields whose types can't be merged
+// are reported like mergeStruct does, or left out when ignoreMismatch is set.
+func applyFields(target any, patch any, fields []string, ignoreMismatch bool) (map[string]any, error) {
+	dst := reflect.Indirect(reflect.ValueOf(target))
+	src := reflect.ValueOf(patch)
+	for src.Kind() == reflect.Pointer {
+		src = src.Elem()
+	}
+
+	updates := map[string]any{}
+	for _, name := range fields {
+		dstField := dst.FieldByName(name)
+		srcField := src.FieldByName(name)
+		if !dstField.IsValid() || !srcField.IsValid() || !dstField.CanSet() {
+			continue
+		}
+
+		if err := setField(dstField, srcField, name); err != nil {
+			if ignoreMismatch {
+				log.Warn("Skipping field while patching: ", err)
+				continue
+			}
+			return nil, err
+		}
+
+		updates[name] = dstField.Interface()
+	}
+
+	return updates, nil
+}
+
+// bindError explains why binding failed for malformed JSON, so clients can tell where parsing broke or which field
+// had the wrong type. The returned error always wraps ErrorInvalidData.
+func bindError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("%w: malformed JSON at offset %d: %s", ErrorInvalidData, syntaxErr.Offset, syntaxErr)
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: malformed JSON, unexpected end of input", ErrorInvalidData)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("%w: field %s should be %s, got %s", ErrorInvalidData, typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+
+	return ErrorInvalidData
+}
+
+// setRawBody reads the request body, which must be valid JSON, into the named field of target.
+func setRawBody(c echo.Context, target any, name string) error {
+	field := reflect.Indirect(reflect.ValueOf(target)).FieldByName(name)
+	if !field.IsValid() || !field.CanSet() {
+		return fmt.Errorf("no settable field %s", name)
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	if !json.Valid(body) {
+		return errors.New("body is not valid JSON")
+	}
+
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(string(body))
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+		field.SetBytes(body)
+	default:
+		return fmt.Errorf("field %s must be a string or byte slice", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// selected returns the selectable columns requested with ?fields=id,name, ignoring any other columns. It is empty
+// when nothing selectable was requested, in which case all columns are loaded.
+func (r *Resource[T]) selected(c echo.Context) []string {
+	param := c.QueryParam("fields")
+	if param == "" || len(r.selectableFields) == 0 {
+		return nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(param, ",") {
+		column = strings.TrimSpace(column)
+		if contains(r.selectableFields, column) && !contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
+
+// selectedFields returns the names used in JSON for the selected columns, or nil when all fields are included.
+func (r *Resource[T]) selectedFields(c echo.Context) map[string]bool {
+	columns := r.selected(c)
+	if len(columns) == 0 {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: r.database()}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil
+	}
+
+	fields := map[string]bool{}
+	for _, column := range columns {
+		if field, ok := stmt.Schema.FieldsByDBName[column]; ok {
+			name, _, _ := jsonFieldName(field.StructField)
+			fields[name] = true
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,19 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// filtered restricts q to the entities matching the query parameters named after filterable columns, such as
+// ?status=active. Several values for the same column match any of them, and other parameters are ignored.
+func (r *Resource[T]) filtered(c echo.Context, q *gorm.DB) *gorm.DB {
+	params := c.QueryParams()
+	for _, column := range r.filterableFields {
+		if values, ok := params[column]; ok && len(values) > 0 {
+			q = q.Where(whereIn(column, values))
+		}
+	}
+
+	return q
+}
@@ -0,0 +1,59 @@
+package minimal
+
+import (
+	"github.com/kaiaverkvist/minimal/database"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+)
+
+// Statuses reported by the health check.
+const (
+	HealthOk          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+type HealthResponse struct {
+	Status string
+
+	// Whether the database responds to pings.
+	Database bool
+
+	// Whether the database has been set up, including migrations.
+	Initialized bool
+}
+
+/*
+EnableHealthCheck registers a health check at path for load balancers and probes, e.g. "/healthz". It responds with
+200 while the database can be pinged, and 503 when it can't. Without a database configured the server is reported as
+degraded, but still up. Combine with Skip and PublicRoutes to keep it reachable past authentication:
+
+	s.EnableHealthCheck("/healthz")
+*/
+func (s *Server) EnableHealthCheck(path string) {
+	s.e.GET(path, func(c echo.Context) error {
+		db := s.db
+		if db == nil {
+			db = database.Db
+		}
+
+		if db == nil {
+			return c.JSON(http.StatusOK, HealthResponse{Status: HealthDegraded})
+		}
+
+		if err := database.Ping(c.Request().Context(), db); err != nil {
+			log.Error("Health check failed: ", err)
+			return c.JSON(http.StatusServiceUnavailable, HealthResponse{
+				Status:      HealthUnavailable,
+				Initialized: database.IsInitialized,
+			})
+		}
+
+		return c.JSON(http.StatusOK, HealthResponse{
+			Status:      HealthOk,
+			Database:    true,
+			Initialized: database.IsInitialized,
+		})
+	})
+}
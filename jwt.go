@@ -0,0 +1,76 @@
+package minimal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"net/http"
+)
+
+const jwtContextKey = "minimal.jwt"
+
+var (
+	ErrorUnauthorized = errors.New("unauthorized")
+)
+
+/*
+JWT returns a middleware authenticating requests by the HS256 signed bearer token in their Authorization header.
+Requests without a valid token get 401 Unauthorized. The claims of the token are parsed into a new value from
+claimsFactory, which predicates read back using Claims:
+
+	type UserClaims struct {
+		jwt.StandardClaims
+		Role string
+	}
+
+	e.Use(minimal.JWT(secret, func() jwt.Claims { return &UserClaims{} }))
+*/
+func JWT(secret []byte, claimsFactory func() jwt.Claims) echo.MiddlewareFunc {
+	return middleware.JWTWithConfig(middleware.JWTConfig{
+		ContextKey: jwtContextKey,
+		ParseTokenFunc: func(auth string, c echo.Context) (interface{}, error) {
+			token, err := jwt.ParseWithClaims(auth, claimsFactory(), func(token *jwt.Token) (interface{}, error) {
+				// Don't let the token pick a signing method the secret wasn't meant for.
+				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+					return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+				}
+
+				return secret, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if !token.Valid {
+				return nil, errors.New("invalid token")
+			}
+
+			return token, nil
+		},
+		ErrorHandlerWithContext: func(err error, c echo.Context) error {
+			return failCode(c, http.StatusUnauthorized, ErrorUnauthorized)
+		},
+	})
+}
+
+/*
+Claims returns the claims of the token authenticated by JWT, and whether there are claims of type C:
+
+	api.CanDeleteById(func(c echo.Context, entity Post) bool {
+		claims, ok := minimal.Claims[*UserClaims](c)
+		return ok && claims.Role == "admin"
+	})
+*/
+func Claims[C jwt.Claims](c echo.Context) (C, bool) {
+	var claims C
+
+	token, ok := c.Get(jwtContextKey).(*jwt.Token)
+	if !ok {
+		return claims, false
+	}
+
+	claims, ok = token.Claims.(C)
+	return claims, ok
+}
@@ -0,0 +1,42 @@
+package minimal
+
+import (
+	"gorm.io/gorm"
+	"reflect"
+)
+
+// columnValue returns the value of the field of model stored in column, if it has one which is set.
+func columnValue(q *gorm.DB, model any, column string) (any, bool) {
+	stmt := &gorm.Statement{DB: q}
+	if err := stmt.Parse(model); err != nil {
+		return nil, false
+	}
+
+	field := stmt.Schema.LookUpField(column)
+	if field == nil {
+		return nil, false
+	}
+
+	value, zero := field.ValueOf(reflect.Indirect(reflect.ValueOf(model)))
+	return value, !zero
+}
+
+// generateID sets the primary key of model to a value from generate when it is still empty.
+func generateID(q *gorm.DB, model any, generate func() any) error {
+	stmt := &gorm.Statement{DB: q}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(model))
+	if _, zero := field.ValueOf(value); !zero {
+		return nil
+	}
+
+	return field.Set(value, generate())
+}
@@ -0,0 +1,24 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+const maxListSizeContextKey = "minimal.maxListSize"
+
+// MaxListSize caps how many entities a resource list responds with, also for overridden list queries.
+// Lists exceeding it are truncated and a warning is logged. Set up by Config.MaxListSize.
+func MaxListSize(n int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(maxListSizeContextKey, n)
+			return next(c)
+		}
+	}
+}
+
+// maxListSize returns the list size cap for the request, or 0 when there is none.
+func maxListSize(c echo.Context) int {
+	n, _ := c.Get(maxListSizeContextKey).(int)
+	return n
+}
@@ -0,0 +1,68 @@
+package minimal
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// lockVersion finds the version field of model used for optimistic locking, along with the version the write expects
+// the row to be at. That is the version sent by the client when the write bind type has the field, and otherwise the
+// version that was loaded.
+func lockVersion(c echo.Context, q *gorm.DB, model any, bound any, name string) (*schema.Field, int64, error) {
+	stmt := &gorm.Statement{DB: q}
+	if err := stmt.Parse(model); err != nil {
+		return nil, 0, err
+	}
+
+	field := stmt.Schema.LookUpField(name)
+	if field == nil {
+		return nil, 0, fmt.Errorf("unknown optimistic lock field %s", name)
+	}
+
+	loaded, _ := field.ValueOf(reflect.Indirect(reflect.ValueOf(model)))
+	expected, ok := toInt64(reflect.ValueOf(loaded))
+	if !ok {
+		return nil, 0, fmt.Errorf("optimistic lock field %s must be an integer", name)
+	}
+
+	sent := reflect.Indirect(reflect.ValueOf(bound)).FieldByName(field.Name)
+	if !sent.IsValid() {
+		return field, expected, nil
+	}
+
+	// PUT and PATCH know which fields were sent, other writes only merge non-zero fields.
+	fields, known := presentFields(c)
+	if (known && contains(fields, field.Name)) || (!known && !sent.IsZero()) {
+		if version, ok := toInt64(reflect.Indirect(sent)); ok {
+			expected = version
+		}
+	}
+
+	return field, expected, nil
+}
+
+// toInt64 converts integers of any size to int64.
+func toInt64(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+
+	return 0, false
+}
+
+// forUpdate locks the rows read by q until the transaction ends, so concurrent writes to the same row wait for each
+// other rather than overwriting each other's changes. SQLite has no row locks, and serializes writes anyway.
+func forUpdate(q *gorm.DB) *gorm.DB {
+	if q.Dialector.Name() == "sqlite" {
+		return q
+	}
+
+	return q.Session(&gorm.Session{}).Clauses(clause.Locking{Strength: "UPDATE"})
+}
@@ -0,0 +1,139 @@
+package minimal
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// metrics are the Prometheus metrics of a server. Every server has its own registry, so several servers, such as in
+// tests, don't collide.
+type metrics struct {
+	registry *prometheus.Registry
+
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	queries      *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	labels := []string{"method", "route", "status"}
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "minimal_http_requests_total",
+			Help: "Number of HTTP requests handled.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "minimal_http_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "minimal_http_response_size_bytes",
+			Help:    "Size of HTTP response bodies.",
+			Buckets: prometheus.ExponentialBuckets(100, 10, 6),
+		}, labels),
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "minimal_db_queries_total",
+			Help: "Number of database statements run, by table and operation.",
+		}, []string{"table", "operation"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.responseSize,
+		m.queries,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// middleware records every request, labeled by the route it matched rather than the path, to keep the number of
+// series bounded.
+func (m *metrics) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		// Errors returned to echo haven't been written yet, so their status has to be worked out here.
+		status := c.Response().Status
+		if err != nil {
+			status = http.StatusInternalServerError
+
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			}
+		}
+
+		labels := prometheus.Labels{
+			"method": c.Request().Method,
+			"route":  c.Path(),
+			"status": strconv.Itoa(status),
+		}
+		m.requests.With(labels).Inc()
+		m.duration.With(labels).Observe(time.Since(start).Seconds())
+		m.responseSize.With(labels).Observe(float64(c.Response().Size))
+
+		return err
+	}
+}
+
+// instrument counts the statements run on db.
+func (m *metrics) instrument(db *gorm.DB) {
+	count := func(operation string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			m.queries.With(prometheus.Labels{"table": tx.Statement.Table, "operation": operation}).Inc()
+		}
+	}
+
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().After("gorm:create").Register("minimal:metrics", count("create")),
+		callbacks.Query().After("gorm:query").Register("minimal:metrics", count("query")),
+		callbacks.Update().After("gorm:update").Register("minimal:metrics", count("update")),
+		callbacks.Delete().After("gorm:delete").Register("minimal:metrics", count("delete")),
+		callbacks.Row().After("gorm:row").Register("minimal:metrics", count("row")),
+		callbacks.Raw().After("gorm:raw").Register("minimal:metrics", count("raw")),
+	} {
+		if err != nil {
+			log.Error("Unable to count database statements: ", err)
+		}
+	}
+}
+
+/*
+EnableMetrics serves Prometheus metrics at path, e.g. "/metrics". Every request is counted and timed, and the size of
+its response recorded, labeled by method, route and status. Database statements are counted by table and operation:
+
+	s.EnableMetrics("/metrics")
+
+Combine with Skip and PublicRoutes to keep it reachable by the scraper past authentication, or keep it from the
+public internet.
+*/
+func (s *Server) EnableMetrics(path string) {
+	if s.metrics != nil {
+		return
+	}
+
+	s.metrics = newMetrics()
+	s.e.Use(s.metrics.middleware)
+	s.e.GET(path, echo.WrapHandler(promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{})))
+
+	// Without a database yet, the statements are counted once it has been set up.
+	if s.db != nil {
+		s.metrics.instrument(s.db)
+	}
+}
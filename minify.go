@@ -0,0 +1,147 @@
+package minimal
+
+import (
+	"bytes"
+	"github.com/labstack/echo/v4"
+	"github.com/tdewolff/minify"
+	"github.com/tdewolff/minify/css"
+	"github.com/tdewolff/minify/html"
+	"github.com/tdewolff/minify/js"
+	"github.com/tdewolff/minify/json"
+	"github.com/tdewolff/minify/svg"
+	"github.com/tdewolff/minify/xml"
+	"mime"
+	"net/http"
+	"regexp"
+)
+
+// MinifyConfig minifies CSS, HTML, JavaScript, JSON, SVG and XML responses. Minification is disabled unless Enabled.
+type MinifyConfig struct {
+	Enabled bool
+
+	// ExcludeContentTypes are sent as is, e.g. "application/json" to keep the exact bytes of API responses while
+	// still minifying HTML. Types ending with a slash exclude the whole family, e.g. "text/".
+	ExcludeContentTypes []string
+}
+
+func newMinifier() *minify.M {
+	m := minify.New()
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("text/html", html.Minify)
+	m.AddFunc("image/svg+xml", svg.Minify)
+	m.AddFuncRegexp(regexp.MustCompile("^(application|text)/(x-)?(java|ecma)script$"), js.Minify)
+	// Numeric tokens are copied verbatim, so large integers keep their precision.
+	m.AddFuncRegexp(regexp.MustCompile("[/+]json$"), json.Minify)
+	m.AddFuncRegexp(regexp.MustCompile("[/+]xml$"), xml.Minify)
+
+	return m
+}
+
+// Minify minifies responses of the supported content types which aren't excluded. Minifiable responses are held back
+// until complete, and sent as they are if they can't be minified. Add it after Gzip, so that responses are minified
+// before they are compressed.
+func Minify(config MinifyConfig) echo.MiddlewareFunc {
+	m := newMinifier()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().Method == http.MethodHead {
+				return next(c)
+			}
+
+			resp := c.Response()
+			writer := &minifyWriter{ResponseWriter: resp.Writer, m: m, config: config, status: http.StatusOK}
+			resp.Writer = writer
+			defer func() {
+				writer.close()
+				resp.Writer = writer.ResponseWriter
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+// minifyWriter buffers minifiable responses, as minifiers need the whole content.
+type minifyWriter struct {
+	http.ResponseWriter
+	m      *minify.M
+	config MinifyConfig
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buffering   bool
+	buf         bytes.Buffer
+}
+
+func (w *minifyWriter) WriteHeader(code int) {
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *minifyWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.decide()
+	}
+
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been written so far, unless the response is being held back for minification.
+func (w *minifyWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+
+	if w.buffering {
+		return
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// decide buffers the response if it is to be minified, or otherwise sends the header so it can be passed through.
+func (w *minifyWriter) decide() {
+	w.decided = true
+
+	header := w.Header()
+	mediaType, _, err := mime.ParseMediaType(header.Get(echo.HeaderContentType))
+	if err == nil && header.Get(echo.HeaderContentEncoding) == "" &&
+		!matchesContentType(mediaType, w.config.ExcludeContentTypes) {
+		_, _, minifier := w.m.Match(mediaType)
+		w.buffering = minifier != nil
+	}
+
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// close finishes the response. If the handler wrote nothing at all, no header is sent, leaving the response to the
+// error handler once the original writer has been restored.
+func (w *minifyWriter) close() {
+	if !w.decided {
+		if !w.wroteHeader {
+			return
+		}
+		w.decide()
+	}
+
+	if !w.buffering {
+		return
+	}
+
+	// The original is returned along with the error, so content the minifier can't handle is still sent.
+	body, _ := w.m.Bytes(w.Header().Get(echo.HeaderContentType), w.buf.Bytes())
+
+	w.Header().Del(echo.HeaderContentLength)
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(body)
+}
@@ -1,6 +1,7 @@
 package minimal
 
 import (
+	"context"
 	"fmt"
 	renderer "github.com/kaiaverkvist/echo-jet-template-renderer"
 	"github.com/kaiaverkvist/minimal/database"
@@ -8,20 +9,25 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
-	"github.com/tdewolff/minify"
-	"github.com/tdewolff/minify/css"
-	"github.com/tdewolff/minify/html"
-	"github.com/tdewolff/minify/js"
-	"github.com/tdewolff/minify/json"
-	"github.com/tdewolff/minify/svg"
-	"github.com/tdewolff/minify/xml"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 	"net/http"
-	"regexp"
+	"time"
 )
 
 type Config struct {
 	DSN string
 
+	// Dialect selects the database driver, which is detected from DSN when empty. See database.DetectDialect.
+	Dialect database.Dialect
+
+	// DB is an already configured database handle, which is used instead of opening one using DSN.
+	DB *gorm.DB
+
+	// Replicas are the DSNs of read replicas. Resource reads, such as lists and gets, are spread over them, while
+	// writes stay on the primary. See database.UseReplicas.
+	Replicas []string
+
 	HttpPort uint
 
 	// Whether to use ACME auto-tls.
@@ -30,10 +36,111 @@ type Config struct {
 	CertKeyPath        string
 	CertPrivateKeyPath string
 
+	// Timeouts and limits of the http server, which public facing servers should set to resist slow clients.
+	// ReadTimeout defaults to 30 seconds, the others are unset when zero.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// ShutdownTimeout is how long in-flight requests get to finish when shutting down on SIGINT or SIGTERM.
+	// Defaults to 10 seconds.
+	ShutdownTimeout time.Duration
+
+	// RequestTimeout cancels the context of requests taking longer than this, and responds with 503 Service
+	// Unavailable. Queries using the request context are cancelled along with it. Zero means no timeout.
+	RequestTimeout time.Duration
+
 	// FriendlyLogging makes logging look nice instead of wrapping it into JSON.
 	FriendlyLogging bool
 
 	Domains []string
+
+	// ExternalBaseURL is the public URL of the application, e.g. "https://example.com/api" when a reverse proxy adds a
+	// path prefix. Used for self-referential links such as Location headers. Defaults to the request host.
+	ExternalBaseURL string
+
+	// MaxListSize truncates resource lists longer than this, as a safety net. Zero means no cap.
+	MaxListSize int
+
+	// EnableGzip compresses responses for clients accepting gzip. Only the GzipContentTypes are compressed, so
+	// content which is already compressed, such as images and archives, is sent as is.
+	EnableGzip bool
+
+	// GzipLevel is the gzip compression level, from gzip.BestSpeed to gzip.BestCompression. Defaults to
+	// gzip.DefaultCompression.
+	GzipLevel int
+
+	// GzipMinLength is the response size in bytes below which responses aren't compressed. Setting it, or
+	// GzipContentTypes, also enables gzip compression.
+	GzipMinLength int
+
+	// GzipContentTypes are the content types that are compressed. Defaults to DefaultGzipContentTypes.
+	GzipContentTypes []string
+
+	// Minify minifies responses such as HTML and JSON. Left empty, responses are sent as they are written.
+	Minify MinifyConfig
+
+	// CORS lets browser clients on other origins call the server. Left empty, no CORS headers are sent.
+	CORS CORSConfig
+
+	// RateLimit limits how many requests each client IP can make. Left empty, requests aren't limited.
+	RateLimit RateLimitConfig
+
+	// APIPrefix mounts every resource under a common path, e.g. "/api/v1". Resources can override it with
+	// Resource.SetBasePath.
+	APIPrefix string
+
+	// ResourceMiddlewares are run for every resource the server registers, before the resource's own middlewares.
+	ResourceMiddlewares []echo.MiddlewareFunc
+
+	// Cache is shared by the caching features, such as Resource.EnableCache. Defaults to an in-memory cache.
+	Cache Cache
+
+	// Validator is used by echo.Context.Validate, which panics without one.
+	Validator echo.Validator
+
+	// JSONSerializer replaces encoding/json for JSON responses and binding, e.g. with an adapter for a faster JSON
+	// library. Defaults to echo's encoding/json based serializer.
+	JSONSerializer echo.JSONSerializer
+
+	// Mounts serve additional filesystems, such as static assets, each under their own path prefix.
+	Mounts []Mount
+
+	// APIOnly skips installing the template renderer, for JSON-only backends. Init accepts a nil filesystem either way.
+	APIOnly bool
+
+	// DebugBodyLogging logs request and response bodies with secrets redacted. Never enable this in production.
+	DebugBodyLogging bool
+
+	// EnableCSRF protects form posts with a CSRF token.
+	EnableCSRF bool
+
+	// CSRFTokenLookup is where the token is read from, e.g. "form:_csrf". Defaults to "form:_csrf".
+	CSRFTokenLookup string
+
+	// CSRFExemptPrefixes are path prefixes skipped by the CSRF protection, such as bearer token authenticated APIs.
+	CSRFExemptPrefixes []string
+
+	// TablePrefix is prepended to every table name.
+	TablePrefix string
+
+	// NamingStrategy fully decides table and column names, taking precedence over TablePrefix.
+	NamingStrategy schema.Namer
+
+	// DisableAutoMigrate skips all automigration, both of models and resources, for when migrations are a separate step.
+	DisableAutoMigrate bool
+
+	// ConnMaxIdleTime closes pooled database connections that have been idle for longer than this. Zero keeps them.
+	ConnMaxIdleTime time.Duration
+
+	// HealthCheckInterval enables a background database ping loop when non-zero.
+	HealthCheckInterval time.Duration
+
+	// HealthCheckFailures is how many consecutive pings may fail before the database is marked as not ready.
+	// Defaults to 3.
+	HealthCheckFailures int
 }
 
 var (
@@ -43,18 +150,34 @@ var (
 		AutoTLS:         false,
 		Domains:         []string{},
 		FriendlyLogging: true,
+		CORS:            DevelopmentCORS,
 	}
 )
 
 const (
 	friendlyHeader = "⇨ ${time_rfc3339} (${short_file}:${line}) ${level}  "
-	requestHeader  = "⇨ ${time_rfc3339} HTTP  ${method} ${uri} -> RESP ${status} (took ${latency_human}) (▼${bytes_in}B  ▲${bytes_out}B)\n"
+	requestHeader  = "⇨ ${time_rfc3339} HTTP  ${method} ${uri} [${id}] -> RESP ${status} (took ${latency_human}) (▼${bytes_in}B  ▲${bytes_out}B)\n"
 )
 
 type Provider interface {
 	Register(e *echo.Echo)
 }
 
+// Implemented by Resource, so that the server can hand down Config.ResourceMiddlewares before registering it.
+type middlewareInheritor interface {
+	inheritMiddlewares(m []echo.MiddlewareFunc)
+}
+
+// Implemented by Resource, so that the server can hand down Config.APIPrefix before registering it.
+type basePathInheritor interface {
+	inheritBasePath(prefix string)
+}
+
+// Implemented by Resource, so that the server can hand down its database before registering it.
+type databaseUser interface {
+	useDatabase(db *gorm.DB)
+}
+
 type Server struct {
 	e *echo.Echo
 
@@ -66,6 +189,15 @@ type Server struct {
 
 	// Server configuration
 	config Config
+
+	// Database handed down to resources, the global database.Db is only kept for backwards compatibility.
+	db *gorm.DB
+
+	// Prometheus metrics, when enabled.
+	metrics *metrics
+
+	// Stops the database health check, when it has been started.
+	stopHealthCheck func()
 }
 
 /*
@@ -105,6 +237,10 @@ This is a 'minimal' example of how to configure the library:
 	}
 */
 func New(config Config, routes []Provider, models []any) Server {
+	if config.Cache == nil {
+		config.Cache = NewMemoryCache()
+	}
+
 	return Server{
 		e: echo.New(),
 
@@ -117,52 +253,227 @@ func New(config Config, routes []Provider, models []any) Server {
 func (s *Server) Init(fs http.FileSystem) {
 	Logging(s.e, s.config.FriendlyLogging)
 
-	if s.config.DSN != "" {
-		_, err := database.InitDatabase(s.config.DSN)
+	s.initDatabase()
+	s.setup(fs)
+
+	address := fmt.Sprintf(":%d", s.config.HttpPort)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Start(s.e, address, s.config.AutoTLS, s.config.CertKeyPath, s.config.CertPrivateKeyPath, s.config.Domains, server.Limits{
+			ReadTimeout:       s.config.ReadTimeout,
+			ReadHeaderTimeout: s.config.ReadHeaderTimeout,
+			WriteTimeout:      s.config.WriteTimeout,
+			IdleTimeout:       s.config.IdleTimeout,
+			MaxHeaderBytes:    s.config.MaxHeaderBytes,
+		})
+	}()
+
+	// Drain in-flight requests on SIGINT and SIGTERM, for zero downtime deploys.
+	server.ShutdownOnSignal(s.e, s.config.ShutdownTimeout, done)
+	s.stopBackground()
+}
+
+// Shutdown stops the server from accepting new connections, and waits until in-flight requests have finished or ctx
+// is done. Init returns once the server has shut down.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopBackground()
+	return s.e.Shutdown(ctx)
+}
+
+// stopBackground stops the work the server runs in the background, such as the database health check.
+func (s *Server) stopBackground() {
+	if s.stopHealthCheck != nil {
+		s.stopHealthCheck()
+	}
+}
+
+func (s *Server) initDatabase() {
+	if s.config.DB != nil {
+		database.UseDB(s.config.DB)
+		s.db = s.config.DB
+	} else if s.config.DSN != "" {
+		if s.config.NamingStrategy != nil {
+			database.SetNamer(s.config.NamingStrategy)
+		} else if s.config.TablePrefix != "" {
+			database.SetNamer(schema.NamingStrategy{TablePrefix: s.config.TablePrefix})
+		}
+
+		db, err := database.InitDialect(s.config.Dialect, s.config.DSN)
 		if err != nil {
 			log.Fatal("Unable to connect to database: ", err)
 			return
 		}
-
-		// Migrate all the models
-		for _, model := range s.models {
-			database.AutoMigrate(model)
-		}
+		s.db = db
 	} else {
 		log.Info("Skipping database setup, no DSN specified")
+		return
 	}
 
+	if len(s.config.Replicas) > 0 {
+		if err := database.UseReplicas(s.db, s.config.Dialect, s.config.Replicas); err != nil {
+			log.Fatal("Unable to connect to read replicas: ", err)
+			return
+		}
+	}
+
+	if s.metrics != nil {
+		s.metrics.instrument(s.db)
+	}
+
+	if s.config.ConnMaxIdleTime > 0 {
+		if err := database.SetConnMaxIdleTime(s.config.ConnMaxIdleTime); err != nil {
+			log.Error("Unable to set connection max idle time: ", err)
+		}
+	}
+
+	if s.config.HealthCheckInterval > 0 {
+		failures := s.config.HealthCheckFailures
+		if failures <= 0 {
+			failures = 3
+		}
+		s.stopHealthCheck = database.StartHealthCheck(s.db, s.config.HealthCheckInterval, failures)
+	}
+
+	s.migrate()
+}
+
+func (s *Server) migrate() {
+	database.SetAutoMigrate(!s.config.DisableAutoMigrate)
+
+	// Migrate all the models
+	for _, model := range s.models {
+		if err := database.AutoMigrateOn(s.db, model); err != nil {
+			log.Fatal("Unable to migrate model: ", err)
+		}
+	}
+}
+
+// setup adds the middlewares, routes and renderer to the echo instance.
+func (s *Server) setup(fs http.FileSystem) {
 	AddMiddlewares(s.e)
+
+	if s.config.CORS.enabled() {
+		s.e.Use(CORS(s.config.CORS))
+	}
+
+	if s.config.RateLimit.enabled() {
+		s.e.Use(RateLimit(s.config.RateLimit))
+	}
+
+	if s.config.RequestTimeout > 0 {
+		s.e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+			Timeout: s.config.RequestTimeout,
+		}))
+	}
+	s.e.Use(UseCache(s.config.Cache))
+
+	// Compression wraps the middlewares added after it, so responses are compressed after they've been minified.
+	if s.config.EnableGzip || s.config.GzipMinLength > 0 || len(s.config.GzipContentTypes) > 0 {
+		s.e.Use(Gzip(GzipConfig{
+			Level:        s.config.GzipLevel,
+			MinLength:    s.config.GzipMinLength,
+			ContentTypes: s.config.GzipContentTypes,
+		}))
+	}
+
+	if s.config.Minify.Enabled {
+		s.e.Use(Minify(s.config.Minify))
+	}
+
+	if s.config.Validator != nil {
+		s.e.Validator = s.config.Validator
+	}
+
+	if s.config.JSONSerializer != nil {
+		s.e.JSONSerializer = s.config.JSONSerializer
+	}
+
+	if s.config.ExternalBaseURL != "" {
+		s.e.Use(BaseURL(s.config.ExternalBaseURL))
+	}
+
+	if s.config.MaxListSize > 0 {
+		s.e.Use(MaxListSize(s.config.MaxListSize))
+	}
+
+	if s.config.DebugBodyLogging {
+		log.Warn("Request and response bodies are being logged, this should never be enabled in production")
+		s.e.Use(BodyLogging(BodyLogConfig{}))
+	}
+
+	if s.config.EnableCSRF {
+		tokenLookup := s.config.CSRFTokenLookup
+		if tokenLookup == "" {
+			tokenLookup = "form:_csrf"
+		}
+		CSRF(s.e, tokenLookup, s.config.CSRFExemptPrefixes)
+	}
+
 	s.registerRoutes()
 
+	for _, mount := range s.config.Mounts {
+		registerMount(s.e, mount)
+	}
+
 	// Sets the Jet renderer up.
-	if fs != nil {
+	if s.config.APIOnly {
+		log.Info("Template rendering is disabled in API only mode")
+	} else if fs != nil {
 		log.Info("Template rendering has been enabled")
-		s.e.Renderer = renderer.NewTemplateRenderer("www", fs)
-	}
+		tr := renderer.NewTemplateRenderer("www", fs)
 
-	address := fmt.Sprintf(":%d", s.config.HttpPort)
-	server.Start(s.e, address, s.config.AutoTLS, s.config.CertKeyPath, s.config.CertPrivateKeyPath, s.config.Domains)
+		// Makes the token available to forms as {{ csrf }}.
+		if s.config.EnableCSRF {
+			tr.SetRenderHook(func(c *echo.Context, data *map[string]interface{}) {
+				(*data)[csrfContextKey] = CSRFToken(*c)
+			})
+		}
+
+		s.e.Renderer = tr
+	}
 }
 
 func (s *Server) Echo() *echo.Echo {
 	return s.e
 }
 
+// DB returns the database of the server, for use by custom providers. Nil until the database has been set up.
+func (s *Server) DB() *gorm.DB {
+	return s.db
+}
+
+// Cache returns the cache shared by the server, for use by custom providers.
+func (s *Server) Cache() Cache {
+	return s.config.Cache
+}
+
+// ServeHTTP lets the server be used as a http.Handler, e.g. with httptest.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.e.ServeHTTP(w, r)
+}
+
 func (s *Server) registerRoutes() {
 	for _, provider := range s.providers {
+		if inheritor, ok := provider.(middlewareInheritor); ok {
+			inheritor.inheritMiddlewares(s.config.ResourceMiddlewares)
+		}
+
+		if inheritor, ok := provider.(basePathInheritor); ok && s.config.APIPrefix != "" {
+			inheritor.inheritBasePath(s.config.APIPrefix)
+		}
+
+		if user, ok := provider.(databaseUser); ok && s.db != nil {
+			user.useDatabase(s.db)
+		}
+
 		provider.Register(s.e)
 	}
 }
 
 func AddMiddlewares(e *echo.Echo) {
-	m := minify.New()
-	m.AddFunc("text/css", css.Minify)
-	m.AddFunc("text/html", html.Minify)
-	m.AddFunc("image/svg+xml", svg.Minify)
-	m.AddFuncRegexp(regexp.MustCompile("^(application|text)/(x-)?(java|ecma)script$"), js.Minify)
-	m.AddFuncRegexp(regexp.MustCompile("[/+]json$"), json.Minify)
-	m.AddFuncRegexp(regexp.MustCompile("[/+]xml$"), xml.Minify)
+	// Tags every request with an ID, kept from the X-Request-ID header when the client or a proxy sent one.
+	e.Use(middleware.RequestID())
 
 	// Panics shouldn't kill the server.
 	e.Use(middleware.Recover())
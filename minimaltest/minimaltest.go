@@ -0,0 +1,154 @@
+/*
+Package minimaltest helps write end-to-end tests of resources, running them against a fresh in-memory SQLite database
+so that their queries are exercised for real:
+
+	func TestUsers(t *testing.T) {
+		users := &minimal.Resource[User]{Name: "/users"}
+		users.SetCreateBindType(UserCreate{})
+
+		h := minimaltest.New(t, users)
+		h.Post("/users", UserCreate{Name: "alice"})
+
+		rec := h.Get("/users")
+		list := minimaltest.Decode[[]User](t, rec)
+		assert.Len(t, list.Data, 1)
+	}
+*/
+package minimaltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/kaiaverkvist/minimal"
+	"github.com/kaiaverkvist/minimal/database"
+	"github.com/kaiaverkvist/minimal/res"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Harness is a server with its own in-memory database, which requests can be fired at without listening on a port.
+type Harness struct {
+	t      testing.TB
+	server *minimal.Server
+}
+
+// New sets a server up with providers registered, such as resources, which migrate their own models. The database is
+// closed and the global database reset when the test is done.
+func New(t testing.TB, providers ...minimal.Provider) *Harness {
+	t.Helper()
+
+	db, err := database.InitInMemory()
+	if err != nil {
+		t.Fatalf("Unable to create in-memory database: %s", err)
+	}
+
+	// Registered first, so it runs last, after anything the test registers once the harness exists.
+	t.Cleanup(func() {
+		database.Db = nil
+		database.IsInitialized = false
+
+		// The in-memory database lives as long as a connection to it is open.
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return &Harness{t: t, server: minimal.NewTestServerOn(db, providers, []any{})}
+}
+
+// Migrate migrates models the test needs besides those of the resources, failing the test when it can't.
+func (h *Harness) Migrate(models ...any) {
+	h.t.Helper()
+
+	for _, model := range models {
+		if err := database.AutoMigrateOn(h.DB(), model); err != nil {
+			h.t.Fatalf("Unable to migrate %T: %s", model, err)
+		}
+	}
+}
+
+// Server returns the server under test.
+func (h *Harness) Server() *minimal.Server {
+	return h.server
+}
+
+// Echo returns the echo instance of the server, e.g. to register extra routes.
+func (h *Harness) Echo() *echo.Echo {
+	return h.server.Echo()
+}
+
+// DB returns the in-memory database, e.g. to seed it or to check what a request wrote.
+func (h *Harness) DB() *gorm.DB {
+	return h.server.DB()
+}
+
+// Do fires a request at the server and returns the recorded response. A string or []byte body is sent as is, while
+// anything else is encoded. Either way, the body is sent as JSON.
+func (h *Harness) Do(method string, target string, body any) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	case []byte:
+		reader = bytes.NewBuffer(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			h.t.Fatalf("Unable to encode request body: %s", err)
+		}
+		reader = bytes.NewBuffer(encoded)
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	if reader != nil {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+
+	rec := httptest.NewRecorder()
+	h.server.ServeHTTP(rec, req)
+	return rec
+}
+
+func (h *Harness) Get(target string) *httptest.ResponseRecorder {
+	h.t.Helper()
+	return h.Do(http.MethodGet, target, nil)
+}
+
+func (h *Harness) Post(target string, body any) *httptest.ResponseRecorder {
+	h.t.Helper()
+	return h.Do(http.MethodPost, target, body)
+}
+
+func (h *Harness) Put(target string, body any) *httptest.ResponseRecorder {
+	h.t.Helper()
+	return h.Do(http.MethodPut, target, body)
+}
+
+func (h *Harness) Patch(target string, body any) *httptest.ResponseRecorder {
+	h.t.Helper()
+	return h.Do(http.MethodPatch, target, body)
+}
+
+func (h *Harness) Delete(target string) *httptest.ResponseRecorder {
+	h.t.Helper()
+	return h.Do(http.MethodDelete, target, nil)
+}
+
+// Decode decodes a response written by the res package, failing the test when it isn't one.
+func Decode[T any](t testing.TB, rec *httptest.ResponseRecorder) res.ModelResponse[T] {
+	t.Helper()
+
+	var response res.ModelResponse[T]
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unable to decode response %q: %s", rec.Body.String(), err)
+	}
+
+	return response
+}
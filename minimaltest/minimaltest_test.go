@@ -0,0 +1,57 @@
+package minimaltest
+
+import (
+	"github.com/kaiaverkvist/minimal"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type Note struct {
+	ID   uint
+	Text string
+}
+
+type NoteUpdate struct {
+	Text string
+}
+
+type Tag struct {
+	ID   uint
+	Name string
+}
+
+func TestHarness(t *testing.T) {
+	notes := &minimal.Resource[Note]{Name: "/notes"}
+	notes.SetCreateBindType(NoteUpdate{})
+	notes.SetWriteBindType(NoteUpdate{})
+
+	h := New(t, notes)
+	h.Migrate(&Tag{})
+	assert.True(t, h.DB().Migrator().HasTable(&Tag{}))
+
+	assert.Equal(t, http.StatusOK, h.Post("/notes", NoteUpdate{Text: "first"}).Code)
+	assert.Equal(t, http.StatusOK, h.Post("/notes", `{"Text": "second"}`).Code)
+	assert.Equal(t, http.StatusOK, h.Put("/notes/1", NoteUpdate{Text: "edited"}).Code)
+	assert.Equal(t, http.StatusOK, h.Delete("/notes/2").Code)
+
+	list := Decode[[]Note](t, h.Get("/notes"))
+	assert.True(t, list.Success)
+	assert.Equal(t, []Note{{ID: 1, Text: "edited"}}, list.Data)
+
+	failed := Decode[any](t, h.Get("/notes/2"))
+	assert.False(t, failed.Success)
+	assert.Equal(t, "no_resource_found", failed.Code)
+}
+
+func TestHarness_ClosesDatabase(t *testing.T) {
+	var h *Harness
+	t.Run("test", func(t *testing.T) {
+		h = New(t)
+		assert.NoError(t, h.DB().Exec("SELECT 1").Error)
+	})
+
+	sqlDB, err := h.DB().DB()
+	assert.NoError(t, err)
+	assert.Error(t, sqlDB.Ping())
+}
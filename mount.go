@@ -0,0 +1,37 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"strings"
+)
+
+// Mount serves the files of a filesystem under a path prefix, e.g. a separately built asset bundle.
+type Mount struct {
+	// Prefix is the path the files are served under, e.g. "/assets".
+	Prefix string
+
+	FS http.FileSystem
+
+	// CacheControl is sent as the Cache-Control header for every file, e.g. "public, max-age=31536000, immutable"
+	// for versioned assets. Left out when empty.
+	CacheControl string
+}
+
+// registerMount serves the mount using a file server.
+func registerMount(e *echo.Echo, m Mount) {
+	prefix := strings.TrimSuffix(m.Prefix, "/")
+	fileServer := http.StripPrefix(prefix, http.FileServer(m.FS))
+
+	e.GET(prefix+"/*", func(c echo.Context) error {
+		if m.CacheControl != "" {
+			c.Response().Header().Set(echo.HeaderCacheControl, m.CacheControl)
+		}
+
+		fileServer.ServeHTTP(c.Response(), c.Request())
+		return nil
+	})
+
+	log.Info("Mounted filesystem at ", prefix)
+}
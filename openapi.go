@@ -0,0 +1,338 @@
+package minimal
+
+import (
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenAPIDocument is an OpenAPI 3 document describing the resources of a server.
+type OpenAPIDocument struct {
+	OpenAPI    string                    `json:"openapi"`
+	Info       OpenAPIInfo               `json:"info"`
+	Paths      map[string]map[string]any `json:"paths"`
+	Components OpenAPIComponents         `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]any `json:"schemas"`
+}
+
+// Implemented by Resource, so that the server can describe it in the OpenAPI document.
+type openAPIDescriber interface {
+	describeOpenAPI(doc *OpenAPIDocument)
+}
+
+/*
+EnableOpenAPI serves an OpenAPI 3 document describing the routes of every resource at path, e.g. "/openapi.json", so
+that clients can be generated from it. Schemas are derived from the entity and bind types using reflection, named
+after the Go types and with fields named as they are encoded in JSON:
+
+	s.EnableOpenAPI("/openapi.json")
+
+The document is built on the first request, once every resource has been registered.
+*/
+func (s *Server) EnableOpenAPI(path string) {
+	var once sync.Once
+	var doc *OpenAPIDocument
+	s.e.GET(path, func(c echo.Context) error {
+		once.Do(func() {
+			doc = s.OpenAPI()
+		})
+
+		return c.JSON(http.StatusOK, doc)
+	})
+}
+
+// OpenAPI describes the routes of every registered resource as an OpenAPI 3 document.
+func (s *Server) OpenAPI() *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI:    "3.0.3",
+		Info:       OpenAPIInfo{Title: "API", Version: "1.0.0"},
+		Paths:      map[string]map[string]any{},
+		Components: OpenAPIComponents{Schemas: map[string]any{}},
+	}
+
+	for _, p := range s.providers {
+		if describer, ok := p.(openAPIDescriber); ok {
+			describer.describeOpenAPI(doc)
+		}
+	}
+
+	return doc
+}
+
+// Matches echo path parameters, such as :id.
+var pathParamPattern = regexp.MustCompile(`:(\w+)`)
+
+// describeOpenAPI adds the routes registered by the resource to doc.
+func (r *Resource[T]) describeOpenAPI(doc *OpenAPIDocument) {
+	entity := schemaFor(reflect.TypeOf(new(T)).Elem(), doc.Components.Schemas)
+
+	for path, methods := range r.allowed {
+		fullPath := strings.TrimSuffix(r.routePath(), "/") + path
+		openAPIPath := pathParamPattern.ReplaceAllString(fullPath, "{$1}")
+
+		operations := doc.Paths[openAPIPath]
+		if operations == nil {
+			operations = map[string]any{}
+			doc.Paths[openAPIPath] = operations
+		}
+
+		var parameters []any
+		for _, match := range pathParamPattern.FindAllStringSubmatch(fullPath, -1) {
+			parameters = append(parameters, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+
+		for _, method := range methods {
+			operation := map[string]any{
+				"operationId": operationId(method, fullPath),
+				"tags":        []string{strings.Trim(r.displayName(), "/")},
+				"responses":   r.openAPIResponses(method, path, entity),
+			}
+
+			if parameters != nil {
+				operation["parameters"] = parameters
+			}
+
+			if body := r.openAPIRequestBody(method, path, doc.Components.Schemas); body != nil {
+				operation["requestBody"] = body
+			}
+
+			operations[strings.ToLower(method)] = operation
+		}
+	}
+}
+
+// openAPIRequestBody describes the body a route binds, or returns nil for routes without one.
+func (r *Resource[T]) openAPIRequestBody(method string, path string, schemas map[string]any) map[string]any {
+	var schema map[string]any
+	switch {
+	case method == http.MethodPost && path == "" && r.createBindType != nil:
+		schema = schemaFor(reflect.TypeOf(r.createBindType), schemas)
+	case method == http.MethodPost && path == "/bulk" && r.createBindType != nil:
+		schema = map[string]any{"type": "array", "items": schemaFor(reflect.TypeOf(r.createBindType), schemas)}
+	case (method == http.MethodPut || method == http.MethodPatch) && path == "/:id" && r.writeBindType != nil:
+		schema = schemaFor(reflect.TypeOf(r.writeBindType), schemas)
+	case method == http.MethodPatch && path == "":
+		schema = map[string]any{"type": "object"}
+	default:
+		return nil
+	}
+
+	return map[string]any{
+		"required": true,
+		"content":  map[string]any{echo.MIMEApplicationJSON: map[string]any{"schema": schema}},
+	}
+}
+
+// openAPIResponses describes the successful response of a route, along with the error envelope.
+func (r *Resource[T]) openAPIResponses(method string, path string, entity map[string]any) map[string]any {
+	// What transformers respond with can't be told by reflection.
+	listed := entity
+	if r.listTransformer != nil {
+		listed = map[string]any{}
+	}
+	got := entity
+	if r.getTransformer != nil {
+		got = map[string]any{}
+	}
+
+	var data map[string]any
+	switch {
+	case method == http.MethodHead:
+		return map[string]any{"200": map[string]any{"description": "The number of entities, in the " + HeaderTotalCount + " header."}}
+	case method == http.MethodGet && path == "/count":
+		data = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"Count": map[string]any{"type": "integer", "format": "int64"}},
+		}
+	case method == http.MethodGet && path == "" && r.cursorField != "":
+		data = cursorSchema(listed)
+	case method == http.MethodGet && path == "" && r.maxPerPage > 0:
+		data = paginatedSchema(listed)
+	case method == http.MethodGet && path == "" && r.listTransformer != nil:
+		data = map[string]any{}
+	case method == http.MethodGet && path == "":
+		data = map[string]any{"type": "array", "items": entity}
+	case method == http.MethodGet && path == "/:id",
+		method == http.MethodPost && path == "/:id/restore",
+		method == http.MethodPost && path == "" && r.returnCreatedEntity,
+		(method == http.MethodPut || method == http.MethodPatch) && path == "/:id" && r.returnUpdatedEntity,
+		method == http.MethodDelete && r.returnDeleted:
+		data = got
+	case method == http.MethodGet && (path == "/export" || path == "/:id/:field"):
+		return map[string]any{"200": map[string]any{"description": "The requested content."}}
+	}
+
+	return map[string]any{
+		"200":     map[string]any{"description": "Success.", "content": envelopeContent(true, data)},
+		"default": map[string]any{"description": "Failure.", "content": envelopeContent(false, nil)},
+	}
+}
+
+// envelopeContent describes a response wrapped in res.ModelResponse, with data as its Data.
+func envelopeContent(success bool, data map[string]any) map[string]any {
+	if data == nil {
+		data = map[string]any{"nullable": true}
+	}
+
+	properties := map[string]any{
+		"Success": map[string]any{"type": "boolean", "enum": []bool{success}},
+		"Message": map[string]any{"type": "string"},
+		"Data":    data,
+	}
+	if !success {
+		properties["Code"] = map[string]any{"type": "string"}
+		properties["RequestID"] = map[string]any{"type": "string"}
+	}
+
+	return map[string]any{
+		echo.MIMEApplicationJSON: map[string]any{
+			"schema": map[string]any{"type": "object", "properties": properties},
+		},
+	}
+}
+
+func paginatedSchema(entity map[string]any) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"Items":   map[string]any{"type": "array", "items": entity},
+			"Page":    map[string]any{"type": "integer"},
+			"PerPage": map[string]any{"type": "integer"},
+			"Total":   map[string]any{"type": "integer", "format": "int64"},
+		},
+	}
+}
+
+func cursorSchema(entity map[string]any) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"Items":      map[string]any{"type": "array", "items": entity},
+			"NextCursor": map[string]any{"type": "string"},
+		},
+	}
+}
+
+// operationId names an operation after its method and path, e.g. get_users_id.
+func operationId(method string, path string) string {
+	parts := []string{strings.ToLower(method)}
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == ':' }) {
+		parts = append(parts, strings.ToLower(part))
+	}
+
+	return strings.Join(parts, "_")
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaFor describes how t is encoded in JSON. Named structs are added to schemas and referred to by name, which
+// also keeps recursive types from recursing forever.
+func schemaFor(t reflect.Type, schemas map[string]any) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case deletedAtType:
+		return map[string]any{"type": "string", "format": "date-time", "nullable": true}
+	}
+
+	// Types encoding themselves can't be described by looking at their fields.
+	if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas)}
+	case reflect.Struct:
+		// Anonymous and generic types don't have a usable name, so they are described in place.
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return structSchema(t, schemas)
+		}
+
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := schemas[t.Name()]; !ok {
+			// Claim the name first, so fields referring back to t don't describe it again.
+			schemas[t.Name()] = map[string]any{}
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return ref
+	}
+
+	return map[string]any{}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	addStructProperties(t, schemas, properties)
+
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// addStructProperties adds the fields of t to properties, flattening embedded structs like encoding/json does.
+func addStructProperties(t reflect.Type, schemas map[string]any, properties map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, hasTagName, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && !hasTagName {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct && ft != timeType && ft != deletedAtType {
+				addStructProperties(ft, schemas, properties)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		properties[name] = schemaFor(field.Type, schemas)
+	}
+}
@@ -0,0 +1,56 @@
+package minimal
+
+import (
+	"database/sql"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// PaginatedResponse is the response to listing a paginated resource. Total counts every entity matching the query,
+// not only those on the page.
+type PaginatedResponse struct {
+	Items   any
+	Page    int
+	PerPage int
+	Total   int64
+}
+
+// listPage runs the list query for the page requested by ?page= and ?per_page=, along with a count of all entities.
+// Both run in the same read only transaction, so the count and the page can't disagree due to concurrent writes.
+func (r *Resource[T]) listPage(c echo.Context, q *gorm.DB) (*PaginatedResponse, []T, error) {
+	page, perPage := r.pageParams(c)
+	response := &PaginatedResponse{Page: page, PerPage: perPage}
+
+	var result []T
+	err := q.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(new(T)).Count(&response.Total).Error; err != nil {
+			return err
+		}
+
+		var err error
+		result, err = r.listAllQuery(c, tx.Offset((page-1)*perPage).Limit(perPage))
+		return err
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+
+	return response, result, err
+}
+
+// pageParams reads the 1-based page and the page size, falling back to the defaults when missing or invalid.
+func (r *Resource[T]) pageParams(c echo.Context) (int, int) {
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(c.QueryParam("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = r.defaultPerPage
+	}
+
+	if perPage > r.maxPerPage {
+		perPage = r.maxPerPage
+	}
+
+	return page, perPage
+}
@@ -0,0 +1,60 @@
+package minimal
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// PreloadSpec describes an association loaded along with the entity. Path may be nested using dots, such as
+// "Order.Customer", and Args are passed on as conditions for the association, such as "active = ?", true.
+type PreloadSpec struct {
+	Path string
+	Args []any
+}
+
+// applyPreloads adds every preload to the query.
+func applyPreloads(q *gorm.DB, preloads []PreloadSpec) *gorm.DB {
+	for _, preload := range preloads {
+		q = q.Preload(preload.Path, preload.Args...)
+	}
+
+	return q
+}
+
+// included returns the preloads requested with ?include=author,comments, or all of them when the parameter is
+// absent. Only registered preloads can be included, so clients can't load arbitrary associations.
+func (r *Resource[T]) included(c echo.Context) ([]PreloadSpec, error) {
+	if !c.QueryParams().Has("include") {
+		return r.preloads, nil
+	}
+
+	var preloads []PreloadSpec
+	for _, path := range strings.Split(c.QueryParam("include"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		preload, ok := r.preload(path)
+		if !ok {
+			return nil, fmt.Errorf("%w: can't include %s", ErrorInvalidData, path)
+		}
+
+		preloads = append(preloads, preload)
+	}
+
+	return preloads, nil
+}
+
+// preload finds the registered preload for path.
+func (r *Resource[T]) preload(path string) (PreloadSpec, bool) {
+	for _, preload := range r.preloads {
+		if preload.Path == path {
+			return preload, true
+		}
+	}
+
+	return PreloadSpec{}, false
+}
@@ -0,0 +1,26 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+const principalContextKey = "minimal.principal"
+
+/*
+SetPrincipal stores the authenticated principal, e.g. the current user, in the echo context. Auth middleware calls it
+once the request is authenticated, after which predicates can read it back using Principal:
+
+	api.CanDeleteById(func(c echo.Context, entity Post) bool {
+		user, ok := minimal.Principal[*User](c)
+		return ok && entity.OwnerID == user.ID
+	})
+*/
+func SetPrincipal(c echo.Context, p any) {
+	c.Set(principalContextKey, p)
+}
+
+// Principal returns the principal stored by SetPrincipal, and whether there is one of type P.
+func Principal[P any](c echo.Context) (P, bool) {
+	p, ok := c.Get(principalContextKey).(P)
+	return p, ok
+}
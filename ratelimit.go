@@ -0,0 +1,60 @@
+package minimal
+
+import (
+	"errors"
+	"github.com/kaiaverkvist/minimal/res"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+var (
+	ErrorRateLimited = errors.New("too many requests")
+)
+
+// RateLimitConfig limits how many requests each client IP can make. Rate limiting is disabled while RequestsPerSecond
+// is zero.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained rate of requests allowed per client.
+	RequestsPerSecond float64
+
+	// Burst is how many requests a client may make at once, on top of the rate. Defaults to RequestsPerSecond rounded
+	// up, and at least 1.
+	Burst int
+}
+
+func (c RateLimitConfig) enabled() bool {
+	return c.RequestsPerSecond > 0
+}
+
+// RateLimit returns a middleware responding with 429 Too Many Requests to clients exceeding the limit. The response
+// carries a Retry-After header estimating in seconds, from the rate, when another request will be allowed. Clients
+// are told apart by their IP, as given by echo.Context.RealIP.
+func RateLimit(config RateLimitConfig) echo.MiddlewareFunc {
+	// Echo's store would otherwise truncate rates below 1 to a burst of 0, which denies every request.
+	if config.Burst <= 0 {
+		config.Burst = int(math.Ceil(config.RequestsPerSecond))
+		if config.Burst < 1 {
+			config.Burst = 1
+		}
+	}
+
+	retryAfter := strconv.Itoa(int(math.Ceil(1 / config.RequestsPerSecond)))
+
+	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+		Store: middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:  rate.Limit(config.RequestsPerSecond),
+			Burst: config.Burst,
+		}),
+		ErrorHandler: func(c echo.Context, err error) error {
+			return res.Forbidden(c, err)
+		},
+		DenyHandler: func(c echo.Context, identifier string, err error) error {
+			c.Response().Header().Set(echo.HeaderRetryAfter, retryAfter)
+			return failCode(c, http.StatusTooManyRequests, ErrorRateLimited)
+		},
+	})
+}
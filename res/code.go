@@ -0,0 +1,45 @@
+package res
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+)
+
+// codedError attaches a machine-readable code to an error, without changing its message.
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e codedError) Unwrap() error {
+	return e.err
+}
+
+// WithCode attaches a stable, machine-readable code such as "no_resource_found" to err, which error responses carry
+// in their Code so clients can branch on it rather than on the message.
+func WithCode(code string, err error) error {
+	if code == "" || err == nil {
+		return err
+	}
+
+	return codedError{code: code, err: err}
+}
+
+// ErrorCode returns the code attached to err using WithCode, or an empty string when there is none.
+func ErrorCode(err error) string {
+	var coded codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+
+	return ""
+}
+
+// FailCodeWithCode is FailCode, with a machine-readable code for the error.
+func FailCodeWithCode(c echo.Context, status int, code string, message error) error {
+	return FailCode(c, status, WithCode(code, message))
+}
@@ -0,0 +1,54 @@
+package res
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// Encoder writes the responses of the helpers in this package, deciding the shape of the JSON body.
+type Encoder interface {
+	// EncodeOk responds with a successful result.
+	EncodeOk(c echo.Context, code int, data any) error
+
+	// EncodeFail responds with an error. data holds details about the error, such as messages for each invalid field,
+	// and is nil when there are none.
+	EncodeFail(c echo.Context, code int, err error, data any) error
+}
+
+// EnvelopeEncoder wraps every response in a ModelResponse, i.e. {Success, Message, Data}. Failures also carry the
+// RequestID. It is the default encoder.
+type EnvelopeEncoder struct{}
+
+func (EnvelopeEncoder) EncodeOk(c echo.Context, code int, data any) error {
+	return c.JSON(code, resModel(true, data, nil))
+}
+
+func (EnvelopeEncoder) EncodeFail(c echo.Context, code int, err error, data any) error {
+	response := resModel(false, data, err)
+	response.RequestID = RequestID(c)
+	return c.JSON(code, response)
+}
+
+var encoder Encoder = EnvelopeEncoder{}
+
+/*
+SetEncoder switches the shape of every response written by this package, e.g. to respond with the data as is:
+
+	type FlatEncoder struct{}
+
+	func (FlatEncoder) EncodeOk(c echo.Context, code int, data any) error {
+		return c.JSON(code, data)
+	}
+
+	func (FlatEncoder) EncodeFail(c echo.Context, code int, err error, data any) error {
+		return c.JSON(code, map[string]any{"error": err.Error(), "details": data})
+	}
+
+It is meant to be called once at startup, before serving requests. Passing nil restores the EnvelopeEncoder.
+*/
+func SetEncoder(e Encoder) {
+	if e == nil {
+		e = EnvelopeEncoder{}
+	}
+
+	encoder = e
+}
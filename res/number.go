@@ -0,0 +1,28 @@
+package res
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// StringInt64 is an int64 that is encoded as a JSON string, so that clients parsing JSON numbers as floats don't lose
+// precision on large values such as snowflake IDs. Both strings and numbers are accepted when decoding.
+type StringInt64 int64
+
+func (n StringInt64) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatInt(int64(n), 10))), nil
+}
+
+func (n *StringInt64) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	v, err := strconv.ParseInt(string(bytes.Trim(data, `"`)), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*n = StringInt64(v)
+	return nil
+}
@@ -8,6 +8,12 @@ import (
 type BaseResponse struct {
 	Success bool
 	Message string
+
+	// Code identifies the error for clients, when one was attached using WithCode.
+	Code string `json:",omitempty"`
+
+	// RequestID identifies the request which failed, so it can be found in the logs.
+	RequestID string `json:",omitempty"`
 }
 
 type ModelResponse[T any] struct {
@@ -25,23 +31,59 @@ func resModel[T any](success bool, model T, message error) ModelResponse[T] {
 		BaseResponse: BaseResponse{
 			Success: success,
 			Message: msg,
+			Code:    ErrorCode(message),
 		},
 		Data: model,
 	}
 }
 
+// RequestID returns the ID of the request, as set by the request ID middleware, or an empty string when there is none.
+func RequestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
 func Ok[T any](c echo.Context, model T) error {
-	return c.JSON(http.StatusOK, resModel(true, model, nil))
+	return encoder.EncodeOk(c, http.StatusOK, model)
 }
 
 func OkCode[T any](c echo.Context, code int, model T) error {
-	return c.JSON(code, resModel(true, model, nil))
+	return encoder.EncodeOk(c, code, model)
 }
 
 func FailCode(c echo.Context, code int, message error) error {
-	return c.JSON(code, resModel[any](false, nil, message))
+	return encoder.EncodeFail(c, code, message, nil)
+}
+
+// FailData responds with an error which carries details, such as messages for each invalid field, in Data.
+func FailData[T any](c echo.Context, code int, message error, data T) error {
+	return encoder.EncodeFail(c, code, message, data)
 }
 
 func Fail(c echo.Context, message error) error {
-	return c.JSON(http.StatusInternalServerError, resModel[any](false, nil, message))
+	return encoder.EncodeFail(c, http.StatusInternalServerError, message, nil)
+}
+
+func Created[T any](c echo.Context, model T) error {
+	return encoder.EncodeOk(c, http.StatusCreated, model)
+}
+
+func Accepted[T any](c echo.Context, model T) error {
+	return encoder.EncodeOk(c, http.StatusAccepted, model)
+}
+
+// NoContent responds with 204, which by definition carries no body, so no envelope is written.
+func NoContent(c echo.Context) error {
+	return c.NoContent(http.StatusNoContent)
+}
+
+func NotFound(c echo.Context, message error) error {
+	return encoder.EncodeFail(c, http.StatusNotFound, message, nil)
+}
+
+func Forbidden(c echo.Context, message error) error {
+	return encoder.EncodeFail(c, http.StatusForbidden, message, nil)
 }
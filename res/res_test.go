@@ -0,0 +1,90 @@
+package res
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusHelpers(t *testing.T) {
+	e := echo.New()
+
+	cases := []struct {
+		code int
+		call func(c echo.Context) error
+	}{
+		{http.StatusCreated, func(c echo.Context) error { return Created(c, "x") }},
+		{http.StatusAccepted, func(c echo.Context) error { return Accepted(c, "x") }},
+		{http.StatusNoContent, func(c echo.Context) error { return NoContent(c) }},
+		{http.StatusNotFound, func(c echo.Context) error { return NotFound(c, errors.New("x")) }},
+		{http.StatusForbidden, func(c echo.Context) error { return Forbidden(c, errors.New("x")) }},
+		{http.StatusBadRequest, func(c echo.Context) error { return FailData(c, http.StatusBadRequest, errors.New("x"), "y") }},
+	}
+
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+		assert.Nil(t, tc.call(c))
+		assert.Equal(t, tc.code, rec.Code)
+	}
+}
+
+func TestStringInt64(t *testing.T) {
+	type Entity struct {
+		ID StringInt64
+	}
+
+	b, err := json.Marshal(Entity{ID: 9007199254740993})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ID":"9007199254740993"}`, string(b))
+
+	var fromString, fromNumber Entity
+	assert.Nil(t, json.Unmarshal([]byte(`{"ID":"9007199254740993"}`), &fromString))
+	assert.Nil(t, json.Unmarshal([]byte(`{"ID":9007199254740993}`), &fromNumber))
+	assert.Equal(t, StringInt64(9007199254740993), fromString.ID)
+	assert.Equal(t, StringInt64(9007199254740993), fromNumber.ID)
+}
+
+type flatEncoder struct{}
+
+func (flatEncoder) EncodeOk(c echo.Context, code int, data any) error {
+	return c.JSON(code, data)
+}
+
+func (flatEncoder) EncodeFail(c echo.Context, code int, err error, data any) error {
+	return c.JSON(code, map[string]any{"error": err.Error()})
+}
+
+func TestSetEncoder(t *testing.T) {
+	e := echo.New()
+	respond := func(call func(c echo.Context) error) string {
+		rec := httptest.NewRecorder()
+		assert.Nil(t, call(e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)))
+		return rec.Body.String()
+	}
+
+	SetEncoder(flatEncoder{})
+	defer SetEncoder(nil)
+
+	assert.JSONEq(t, `{"ID":1}`, respond(func(c echo.Context) error { return Ok(c, map[string]int{"ID": 1}) }))
+	assert.JSONEq(t, `{"error":"x"}`, respond(func(c echo.Context) error { return NotFound(c, errors.New("x")) }))
+
+	SetEncoder(nil)
+	assert.JSONEq(t, `{"Success":true,"Message":"","Data":"y"}`, respond(func(c echo.Context) error { return Ok(c, "y") }))
+}
+
+func TestFailCodeWithCode(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+	assert.Nil(t, FailCodeWithCode(c, http.StatusNotFound, "no_thing", errors.New("no thing")))
+	assert.JSONEq(t, `{"Success":false,"Message":"no thing","Code":"no_thing","Data":null}`, rec.Body.String())
+	assert.Equal(t, "no_thing", ErrorCode(fmt.Errorf("wrapped: %w", WithCode("no_thing", errors.New("no thing")))))
+}
@@ -2,15 +2,21 @@ package minimal
 
 import (
 	"errors"
-	patch "github.com/geraldo-labs/merge-struct"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt"
 	"github.com/kaiaverkvist/minimal/database"
 	"github.com/kaiaverkvist/minimal/res"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 )
 
 var (
@@ -20,41 +26,188 @@ var (
 	ErrorNoBindType       = errors.New("unable to handle this request")
 	ErrorInvalidData      = errors.New("bad data")
 	ErrorInvalidID        = errors.New("bad id")
+	ErrorNotReady         = errors.New("database not ready")
+	ErrorAsyncCreate      = errors.New("unable to queue creation")
 )
 
+// AcceptedResponse is the response to an asynchronous create, pointing at where the progress can be followed.
+type AcceptedResponse struct {
+	StatusURL string
+}
+
+// Seconds clients are asked to wait before retrying while the database isn't ready.
+const notReadyRetryAfter = 5
+
+// HeaderTotalCount carries the number of entities in the collection, in responses to HEAD on it.
+const HeaderTotalCount = "X-Total-Count"
+
 // Resource is an automatic REST api module which lets the consumer simply define the resource and it will have
 // associated database code, et.c. automatically set up.
 type Resource[T any] struct {
+	// Name is used when logging about the resource. Defaults to the type name of T.
+	// When RoutePath isn't set, Name is used as the route as well.
 	Name string
 
+	// RoutePath is where the resource is routed, e.g. "/v2/widgets". Defaults to Name, or the lowercased and
+	// pluralized type name of T when neither is set.
+	RoutePath string
+
+	// Prefixed to the route, e.g. "/api/v1". Inherited from Config.APIPrefix unless set.
+	basePath string
+
 	// Hooking into registration, by consumer.
 	onRegister func(e *echo.Echo)
 
+	// Customizes error responses, by consumer.
+	onError func(c echo.Context, op string, err error) error
+
 	// List ALL operation.
 	canListAll   func(c echo.Context) bool
 	listAllQuery func(c echo.Context, q *gorm.DB) ([]T, error)
 
 	// List by ID operation.
 	canListById   func(c echo.Context, entity T) bool
-	listByIdQuery func(c echo.Context, q *gorm.DB, id uint) (*T, error)
+	listByIdQuery func(c echo.Context, q *gorm.DB, id any) (*T, error)
 
 	// Write by ID operation.
 	canWriteById   func(c echo.Context, entity T) bool
 	writeBindType  any
-	writeByIdQuery func(c echo.Context, q *gorm.DB, id uint, new any) error
+	writeByIdQuery func(c echo.Context, q *gorm.DB, id any, new any) error
+
+	// Whether write responds with the updated entity, rather than no content.
+	returnUpdatedEntity bool
 
 	// Create operation.
 	canCreate      func(c echo.Context) bool
 	createBindType any
 
+	// Generates primary keys on create, e.g. ULIDs, instead of leaving it to the database.
+	idGenerator func() any
+
+	// Column looked up by the id in the path, "id" unless set.
+	primaryKeyColumn string
+
+	// Replaces the synchronous insert, for creates kicking off background work.
+	asyncCreate func(c echo.Context, model *T) (string, error)
+
+	// Field of T the raw request body is stored in, instead of binding.
+	rawBodyField string
+
+	// Whether bound fields that can't be merged onto T are skipped, rather than failing the request.
+	ignorePatchMismatches bool
+
+	// Sets of fields which must be unique together, checked before creating.
+	uniqueSets [][]string
+
+	// Whether create responds with the created entity, rather than no content.
+	returnCreatedEntity bool
+
+	// Run around inserting the entity on create.
+	beforeCreate func(c echo.Context, model *T) error
+	afterCreate  func(c echo.Context, model *T) error
+
 	// Used in case patching is not sufficient for creation of the entity
 	createTransformer func(c echo.Context) (*T, error)
 
+	// Map the entities read by list all and get by id to what is responded with.
+	listTransformer func(c echo.Context, items []T) (any, error)
+	getTransformer  func(c echo.Context, item *T) (any, error)
+
+	// Version field checked and bumped by writes, when optimistic locking is used.
+	optimisticLockField string
+
+	// Saves the entity on create.
+	createQuery func(c echo.Context, q *gorm.DB, model *T) error
+
 	// Delete by ID operation.
 	canDeleteById   func(c echo.Context, entity T) bool
 	deleteByIdQuery func(c echo.Context, q *gorm.DB, entity T) error
+	returnDeleted   bool
+
+	// Whether soft-deletable entities are deleted permanently.
+	hardDelete bool
+
+	// Restricts every query to the records the request may see, e.g. those owned by the current user.
+	scope func(c echo.Context, q *gorm.DB) *gorm.DB
+
+	// Associations loaded by the default list and get queries.
+	preloads []PreloadSpec
+
+	// Whether ?with_deleted=true may include soft-deleted records in reads.
+	canViewDeleted func(c echo.Context) bool
+
+	// Whether reads always include soft-deleted records.
+	includeSoftDeleted bool
+
+	// Bulk write operation, only registered when there are writable fields.
+	canBulkWrite       func(c echo.Context) bool
+	bulkWritableFields []string
+	bulkFilterFields   []string
+
+	// Registers the bulk create endpoint.
+	bulkCreateEnabled bool
+
+	// Columns list all can be filtered on using query parameters named after them.
+	filterableFields []string
+
+	// Columns list all can be sorted on using ?sort=.
+	sortableFields []string
+
+	// Columns searched by ?q=.
+	searchableFields []string
+
+	// Column compared against ?updated_since=, "updated_at" unless set.
+	timestampField string
+
+	// Columns clients can restrict list and get responses to using ?fields=.
+	selectableFields []string
+
+	// Page sizes of list all, which is paginated when maxPerPage is set.
+	defaultPerPage int
+	maxPerPage     int
+
+	// Column list all is paginated by, using ?after=, instead of by page number.
+	cursorField string
+
+	// Rows loaded per batch by the export endpoint, which is only registered when set.
+	exportBatchSize int
+
+	// Fields which can be downloaded as files, only registered when there are any.
+	downloadFields []string
+
+	// Parses the :id path parameter into the value handed to the query functions.
+	idParser func(s string) (any, error)
+
+	// Checks the validate struct tags of bound types, the shared validator is used when nil.
+	validator *validator.Validate
+
+	// Per-field read access control, applied when serializing list and single responses.
+	fieldVisibility func(c echo.Context, field string) bool
+
+	// Adds computed fields to serialized entities, after field visibility has been applied.
+	responseDecorator func(c echo.Context, entity T) map[string]any
 
 	middlewares []echo.MiddlewareFunc
+
+	// Database queried by the resource, set by SetDB or handed down by the server. Falls back to the global database.
+	db *gorm.DB
+
+	// Handed down by the server, and run before the resource's own middlewares.
+	inheritedMiddlewares []echo.MiddlewareFunc
+
+	// Adds the deprecation headers, when the resource is deprecated.
+	deprecation echo.MiddlewareFunc
+
+	// Authenticates every request to the resource, when set by UseJWT.
+	jwt echo.MiddlewareFunc
+
+	// Response caching, enabled when cacheTTL is set.
+	cache         Cache
+	cacheTTL      time.Duration
+	fallbackCache Cache
+
+	// Methods registered per path, used to answer OPTIONS requests.
+	allowed map[string][]string
 }
 
 // Register is called when minimal initializes, and will add routes and trigger the automigration.
@@ -67,8 +220,10 @@ func (r *Resource[T]) Register(e *echo.Echo) {
 	if r.listAllQuery == nil {
 		// Default querying function for list all.
 		r.listAllQuery = func(c echo.Context, q *gorm.DB) ([]T, error) {
-			var result []T
-			tx := q.Find(&result)
+			preloads, _ := r.included(c)
+
+			result := make([]T, 0)
+			tx := applyPreloads(q, preloads).Find(&result)
 
 			if tx.Error != nil {
 				return nil, ErrorNoResourceFound
@@ -80,9 +235,11 @@ func (r *Resource[T]) Register(e *echo.Echo) {
 
 	if r.listByIdQuery == nil {
 		// Default for list by id
-		r.listByIdQuery = func(c echo.Context, q *gorm.DB, id uint) (*T, error) {
+		r.listByIdQuery = func(c echo.Context, q *gorm.DB, id any) (*T, error) {
+			preloads, _ := r.included(c)
+
 			var result T
-			tx := q.First(&result, "id = ?", id)
+			tx := applyPreloads(q, preloads).First(&result, r.byId(id))
 
 			if r.canListById != nil {
 				if !r.canListById(c, result) {
@@ -102,10 +259,25 @@ func (r *Resource[T]) Register(e *echo.Echo) {
 		}
 	}
 
+	if r.createQuery == nil {
+		r.createQuery = func(c echo.Context, q *gorm.DB, model *T) error {
+			return q.Create(model).Error
+		}
+	}
+
 	if r.writeByIdQuery == nil {
-		r.writeByIdQuery = func(c echo.Context, q *gorm.DB, id uint, new any) error {
+		r.writeByIdQuery = func(c echo.Context, q *gorm.DB, id any, new any) error {
 			var result T
-			tx := q.First(&result, "id = ?", id)
+			tx := forUpdate(q).First(&result, r.byId(id))
+
+			// Patching onto a zero value would save garbage, or even insert a new row.
+			if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				return ErrorNoResourceFound
+			}
+
+			if tx.Error != nil {
+				return tx.Error
+			}
 
 			if r.canWriteById != nil {
 				if !r.canWriteById(c, result) {
@@ -113,19 +285,60 @@ func (r *Resource[T]) Register(e *echo.Echo) {
 				}
 			}
 
-			_, err := patch.Struct(&result, new)
-			if err != nil {
+			// Only update the row while it is still at the expected version, which the write bumps.
+			var version *schema.Field
+			var expected int64
+			if r.optimisticLockField != "" {
+				var err error
+				version, expected, err = lockVersion(c, q, &result, new, r.optimisticLockField)
+				if err != nil {
+					return err
+				}
+
+				q = q.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: version.DBName}, Value: expected})
+			}
+
+			if err := mergeStruct(&result, new, r.ignorePatchMismatches); err != nil {
 				log.Error("Patching failed: ", err)
-				return ErrorInvalidData
+				return err
+			}
+
+			// Only write the columns the client actually sent, so concurrent changes to other fields survive.
+			// Without knowing which fields were sent, fall back to saving the entire entity.
+			var tx2 *gorm.DB
+			if fields, ok := presentFields(c); ok {
+				updates, err := applyFields(&result, new, fields, r.ignorePatchMismatches)
+				if err != nil {
+					log.Error("Patching failed: ", err)
+					return err
+				}
+
+				if len(updates) == 0 {
+					return nil
+				}
+
+				if version != nil {
+					updates[version.Name] = expected + 1
+				}
+
+				tx2 = q.Model(&result).Updates(updates)
+			} else if version != nil {
+				if err := version.Set(reflect.ValueOf(&result).Elem(), expected+1); err != nil {
+					return err
+				}
+
+				// Save inserts the row when no row matches, so every column is updated instead.
+				tx2 = q.Model(&result).Select("*").Updates(&result)
+			} else {
+				tx2 = q.Save(&result)
 			}
 
-			tx2 := database.Db.Save(result)
 			if tx2.Error != nil {
 				return tx2.Error
 			}
 
-			if tx.Error != nil {
-				return tx.Error
+			if version != nil && tx2.RowsAffected == 0 {
+				return fmt.Errorf("%w: %s %d is out of date", ErrorConflict, version.Name, expected)
 			}
 
 			return nil
@@ -134,7 +347,11 @@ func (r *Resource[T]) Register(e *echo.Echo) {
 
 	if r.deleteByIdQuery == nil {
 		r.deleteByIdQuery = func(c echo.Context, q *gorm.DB, entity T) error {
-			tx := database.Db.Delete(&entity)
+			if r.hardDelete {
+				q = q.Unscoped()
+			}
+
+			tx := q.Delete(&entity)
 
 			if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 				return ErrorNoResourceFound
@@ -148,105 +365,377 @@ func (r *Resource[T]) Register(e *echo.Echo) {
 		}
 	}
 
-	if database.Db != nil {
-		log.Info("Initialized resource: ", r.Name)
-		database.AutoMigrate(new(T))
+	if db := r.database(); db != nil {
+		log.Info("Initialized resource: ", r.displayName())
+		if err := database.AutoMigrateOn(db, new(T)); err != nil {
+			log.Fatal("Unable to migrate resource: ", err)
+		}
 	} else {
 		log.Info("Uninitialized database, skipping..")
 	}
 
-	group := e.Group(r.Name)
-	group.GET("", r.getAll, r.middlewares...)
-	group.GET("/:id", r.getById, r.middlewares...)
-	group.PUT("/:id", r.writeById, r.middlewares...)
-	group.POST("", r.create, r.middlewares...)
-	group.DELETE("/:id", r.deleteById, r.middlewares...)
+	r.checkCache()
+
+	group := e.Group(r.routePath())
+	r.allowed = map[string][]string{}
+	r.route(group, http.MethodGet, "", r.getAll)
+	r.route(group, http.MethodHead, "", r.countAll)
+	r.route(group, http.MethodGet, "/count", r.countAll)
+	r.route(group, http.MethodGet, "/:id", r.getById)
+	r.route(group, http.MethodPut, "/:id", r.writeById)
+	r.route(group, http.MethodPatch, "/:id", r.writeById)
+	r.route(group, http.MethodPost, "", r.create)
+	r.route(group, http.MethodDelete, "/:id", r.deleteById)
+
+	if len(r.bulkWritableFields) > 0 {
+		r.route(group, http.MethodPatch, "", r.bulkWrite)
+	}
+
+	if r.bulkCreateEnabled {
+		r.route(group, http.MethodPost, "/bulk", r.bulkCreate)
+	}
+
+	if softDeleteField(reflect.TypeOf(new(T))) != "" && !r.hardDelete {
+		r.route(group, http.MethodPost, "/:id/restore", r.restoreById)
+	}
+
+	// Exports and downloads are streamed, so they must not be buffered by the response cache.
+	if r.exportBatchSize > 0 {
+		r.handle(group, http.MethodGet, "/export", r.requireDatabase(r.export))
+	}
+
+	if len(r.downloadFields) > 0 {
+		r.handle(group, http.MethodGet, "/:id/:field", r.requireDatabase(r.download))
+	}
+
+	// Answer OPTIONS with the methods that were actually registered on each path.
+	// These deliberately skip the resource middlewares so that preflight requests aren't rejected by e.g. auth.
+	for path, methods := range r.allowed {
+		allow := strings.Join(append(methods, http.MethodOptions), ", ")
+		group.OPTIONS(path, func(c echo.Context) error {
+			c.Response().Header().Set(echo.HeaderAllow, allow)
+			return res.NoContent(c)
+		})
+	}
+}
+
+// displayName returns the name used when logging about the resource.
+func (r *Resource[T]) displayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	return reflect.TypeOf(new(T)).Elem().Name()
+}
+
+// routePath returns the path the resource is routed at, including the base path.
+func (r *Resource[T]) routePath() string {
+	route := r.RoutePath
+	if route == "" {
+		// Name used to double as the route, so keep it working that way.
+		route = r.Name
+	}
+	if route == "" {
+		route = "/" + strings.ToLower(reflect.TypeOf(new(T)).Elem().Name()) + "s"
+	}
+
+	if r.basePath == "" {
+		return route
+	}
+
+	return strings.TrimSuffix(r.basePath, "/") + "/" + strings.TrimPrefix(route, "/")
+}
+
+// route registers the handler on the group with the resource middlewares, and records the method as allowed for path.
+func (r *Resource[T]) route(group *echo.Group, method string, path string, h echo.HandlerFunc) {
+	r.handle(group, method, path, r.requireDatabase(r.cacheHandler(method, h)))
+}
+
+// handle is like route, but registers h as is.
+func (r *Resource[T]) handle(group *echo.Group, method string, path string, h echo.HandlerFunc) {
+	middlewares := append(append([]echo.MiddlewareFunc{}, r.inheritedMiddlewares...), r.middlewares...)
+	if r.jwt != nil {
+		middlewares = append([]echo.MiddlewareFunc{r.jwt}, middlewares...)
+	}
+	if r.deprecation != nil {
+		middlewares = append([]echo.MiddlewareFunc{r.deprecation}, middlewares...)
+	}
+	group.Add(method, path, h, middlewares...)
+	r.allowed[path] = append(r.allowed[path], method)
+}
+
+// requireDatabase short-circuits with 503 while the database isn't ready, instead of querying a nil handle.
+func (r *Resource[T]) requireDatabase(h echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !r.ready() {
+			// Give clients a hint on when to retry, rather than having them hammer us while starting up.
+			c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(notReadyRetryAfter))
+			return failCode(c, http.StatusServiceUnavailable, ErrorNotReady)
+		}
+
+		return h(c)
+	}
 }
 
 func (r *Resource[T]) getAll(c echo.Context) error {
 	// Access control check
 	if r.canListAll != nil {
 		if !r.canListAll(c) {
-			return res.FailCode(c, http.StatusForbidden, ErrorNoResourceAccess)
+			return r.fail(c, OperationListAll, http.StatusForbidden, ErrorNoResourceAccess)
 		}
 	}
 
-	m, err := r.listAllQuery(c, database.Db)
+	q, err := r.readQuery(c)
+	if err != nil {
+		return r.fail(c, OperationListAll, http.StatusForbidden, err)
+	}
+
+	q, err = r.updatedSince(c, r.searched(c, r.filtered(c, q)))
+	if err == nil {
+		q, err = r.sorted(c, q)
+	}
+	if err != nil {
+		return r.fail(c, OperationListAll, http.StatusBadRequest, err)
+	}
+
+	if _, err := r.included(c); err != nil {
+		return r.fail(c, OperationListAll, http.StatusBadRequest, err)
+	}
+
+	var m []T
+	var page *PaginatedResponse
+	var cursorPage *CursorResponse
+	switch {
+	case r.cursorField != "":
+		cursorPage, m, err = r.listAfter(c, q)
+	case r.maxPerPage > 0:
+		page, m, err = r.listPage(c, q)
+	default:
+		m, err = r.listAllQuery(c, q)
+	}
+
 	if err != nil {
 		if errors.Is(err, ErrorNoResourceFound) {
-			return res.FailCode(c, http.StatusNotFound, err)
+			return r.fail(c, OperationListAll, http.StatusNotFound, err)
+		}
+
+		if errors.Is(err, ErrorInvalidData) {
+			return r.fail(c, OperationListAll, http.StatusBadRequest, err)
 		}
 
 		log.Errorf("Could not list all for resource %s: %s", reflect.TypeOf(r), err)
-		return res.FailCode(c, http.StatusInternalServerError, ErrorDatabase)
+		return r.fail(c, OperationListAll, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	// Clients expect an array, even when an overridden query returns nil.
+	if m == nil {
+		m = []T{}
+	}
+
+	// Backstop against accidental full table scans reaching the client.
+	if limit := maxListSize(c); limit > 0 && len(m) > limit {
+		log.Warnf("List all for resource %s returned %d entities, truncating to %d", reflect.TypeOf(r), len(m), limit)
+		m = m[:limit]
+	}
+
+	items, err := transformed(r, c, m, r.listTransformer)
+	if err != nil {
+		return r.failTransform(c, OperationListAll, err)
+	}
+
+	if page != nil {
+		page.Items = items
+		return res.Ok(c, page)
+	}
+
+	if cursorPage != nil {
+		cursorPage.Items = items
+		return res.Ok(c, cursorPage)
 	}
 
-	return res.Ok(c, m)
+	return res.Ok(c, items)
+}
+
+// CountResponse is the number of entities list all would respond with.
+type CountResponse struct {
+	Count int64
+}
+
+// countAll counts the entities list all would respond with, honoring the same filters. HEAD responds with the count
+// in the X-Total-Count header only, and GET /count in the body as well.
+func (r *Resource[T]) countAll(c echo.Context) error {
+	if r.canListAll != nil {
+		if !r.canListAll(c) {
+			return r.fail(c, OperationCountAll, http.StatusForbidden, ErrorNoResourceAccess)
+		}
+	}
+
+	q, err := r.readQuery(c)
+	if err != nil {
+		return r.fail(c, OperationCountAll, http.StatusForbidden, err)
+	}
+
+	q, err = r.updatedSince(c, r.searched(c, r.filtered(c, q)))
+	if err != nil {
+		return r.fail(c, OperationCountAll, http.StatusBadRequest, err)
+	}
+
+	var count int64
+	if tx := q.Model(new(T)).Count(&count); tx.Error != nil {
+		log.Errorf("Could not count all for resource %s: %s", reflect.TypeOf(r), tx.Error)
+		return r.fail(c, OperationCountAll, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	c.Response().Header().Set(HeaderTotalCount, strconv.FormatInt(count, 10))
+	if c.Request().Method == http.MethodHead {
+		return c.NoContent(http.StatusOK)
+	}
+
+	return res.Ok(c, CountResponse{Count: count})
 }
 
 func (r *Resource[T]) getById(c echo.Context) error {
 	// Parse the ID parameter, or fail.
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := r.parseID(c)
 	if err != nil {
-		return res.FailCode(c, http.StatusBadRequest, ErrorInvalidID)
+		return r.fail(c, OperationGetById, http.StatusBadRequest, err)
 	}
 
-	m, err := r.listByIdQuery(c, database.Db, uint(id))
+	q, err := r.readQuery(c)
+	if err != nil {
+		return r.fail(c, OperationGetById, http.StatusForbidden, err)
+	}
+
+	if _, err := r.included(c); err != nil {
+		return r.fail(c, OperationGetById, http.StatusBadRequest, err)
+	}
+
+	m, err := r.listByIdQuery(c, q, id)
 	if err != nil {
 		if errors.Is(err, ErrorNoResourceFound) {
-			return res.FailCode(c, http.StatusNotFound, ErrorNoResourceFound)
+			return r.fail(c, OperationGetById, http.StatusNotFound, ErrorNoResourceFound)
 		}
 
 		// When we don't have access to the resource.
 		if errors.Is(err, ErrorNoResourceAccess) {
-			return res.FailCode(c, http.StatusForbidden, ErrorNoResourceAccess)
+			return r.fail(c, OperationGetById, http.StatusForbidden, ErrorNoResourceAccess)
 		}
 
 		log.Errorf("Could not get by id for resource %s: %s", reflect.TypeOf(r), err)
-		return res.FailCode(c, http.StatusInternalServerError, ErrorDatabase)
+		return r.fail(c, OperationGetById, http.StatusInternalServerError, ErrorDatabase)
 	}
 
-	return res.Ok(c, m)
+	item, err := transformed(r, c, m, r.getTransformer)
+	if err != nil {
+		return r.failTransform(c, OperationGetById, err)
+	}
+
+	return res.Ok(c, item)
 }
 
 func (r *Resource[T]) writeById(c echo.Context) error {
 	// Check that we have a bind type set up already. If not, we must fail the call.
 	if r.writeBindType == nil {
 		log.Error("Cannot write without a bind type set up. Call SetWriteBindType.")
-		return res.FailCode(c, http.StatusInternalServerError, ErrorNoBindType)
+		return r.fail(c, OperationWriteById, http.StatusInternalServerError, ErrorNoBindType)
 	}
 
 	// Try to instantiate the "DTO" type, and bind to it.
 	boundType := reflect.TypeOf(r.writeBindType)
 	boundPtr := reflect.New(boundType)
 	bound := boundPtr.Interface()
-	if err := c.Bind(bound); err != nil {
+	if err := bindTracked(c, bound); err != nil {
 		log.Error("Binding failed: ", err)
-		return res.FailCode(c, http.StatusBadRequest, ErrorInvalidData)
+		return r.fail(c, OperationWriteById, http.StatusBadRequest, bindError(err))
+	}
+
+	// PUT replaces, so every field of the bind type is written, even when left out of the request. PATCH only writes
+	// the fields that were sent, so only those are validated.
+	var err error
+	if c.Request().Method == http.MethodPut {
+		c.Set(presentFieldsContextKey, allFields(boundType))
+		err = r.validate(bound)
+	} else if fields, ok := presentFields(c); ok {
+		err = r.validatePartial(bound, fields)
+	} else {
+		err = r.validate(bound)
+	}
+
+	if err != nil {
+		return r.fail(c, OperationWriteById, http.StatusBadRequest, err)
 	}
 
 	// Parse the ID parameter, or fail.
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := r.parseID(c)
 	if err != nil {
-		return res.FailCode(c, http.StatusBadRequest, ErrorInvalidID)
+		return r.fail(c, OperationWriteById, http.StatusBadRequest, err)
+	}
+
+	// When dry running, the write is rolled back and the entity as it would have been saved is kept for the response.
+	var entity *T
+	if isDryRun(c) {
+		err = dryRun(r.query(c), func(tx *gorm.DB) error {
+			tx = r.scoped(c, tx)
+			if err := r.writeByIdQuery(c, tx, id, bound); err != nil {
+				return err
+			}
+
+			var err error
+			entity, err = r.listByIdQuery(c, tx, id)
+			return err
+		})
+	} else {
+		// The lookup, access check and save of the write query share a transaction, and the default lookup locks the
+		// row, so concurrent writes to it wait their turn rather than losing updates.
+		err = r.query(c).Transaction(func(tx *gorm.DB) error {
+			q := r.scoped(c, tx)
+			if err := r.writeByIdQuery(c, q, id, bound); err != nil {
+				return err
+			}
+
+			// Reload rather than echoing the bound data, so defaults and hooks applied by the database are included.
+			if r.returnUpdatedEntity {
+				var err error
+				entity, err = r.listByIdQuery(c, q, id)
+				return err
+			}
+
+			return nil
+		})
 	}
 
-	err = r.writeByIdQuery(c, database.Db, uint(id), bound)
 	if err != nil {
 		// Tried to write a non existant resource.
 		if errors.Is(err, ErrorNoResourceFound) {
-			return res.FailCode(c, http.StatusNotFound, ErrorNoResourceFound)
+			return r.fail(c, OperationWriteById, http.StatusNotFound, ErrorNoResourceFound)
 		}
 
 		// When we don't have access to the resource.
 		if errors.Is(err, ErrorNoResourceAccess) {
-			return res.FailCode(c, http.StatusForbidden, ErrorNoResourceAccess)
+			return r.fail(c, OperationWriteById, http.StatusForbidden, ErrorNoResourceAccess)
+		}
+
+		// The bound fields couldn't be merged onto the entity.
+		if errors.Is(err, ErrorInvalidData) {
+			return r.fail(c, OperationWriteById, http.StatusBadRequest, err)
+		}
+
+		// Someone else wrote the entity since the client loaded it.
+		if errors.Is(err, ErrorConflict) {
+			return r.fail(c, OperationWriteById, http.StatusConflict, err)
 		}
 
 		log.Errorf("Could not write by id for resource %s: %s", reflect.TypeOf(r), err)
-		return res.FailCode(c, http.StatusInternalServerError, ErrorDatabase)
+		return r.fail(c, OperationWriteById, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	if entity != nil {
+		item, err := transformed(r, c, entity, r.getTransformer)
+		if err != nil {
+			return r.failTransform(c, OperationWriteById, err)
+		}
+
+		return res.Ok(c, item)
 	}
 
 	return c.NoContent(http.StatusOK)
@@ -256,7 +745,7 @@ func (r *Resource[T]) create(c echo.Context) error {
 	// Check that we can actually create the resource.
 	if r.canCreate != nil {
 		if !r.canCreate(c) {
-			return res.FailCode(c, http.StatusForbidden, ErrorNoResourceAccess)
+			return r.fail(c, OperationCreate, http.StatusForbidden, ErrorNoResourceAccess)
 		}
 	}
 
@@ -265,17 +754,22 @@ func (r *Resource[T]) create(c echo.Context) error {
 	if r.createTransformer != nil {
 		transformedModel, err := r.createTransformer(c)
 		if err != nil {
-			return res.FailCode(c, http.StatusBadRequest, err)
+			return r.fail(c, OperationCreate, http.StatusBadRequest, err)
 		}
 
 		if transformedModel != nil {
 			model = *transformedModel
 		}
+	} else if r.rawBodyField != "" {
+		if err := setRawBody(c, &model, r.rawBodyField); err != nil {
+			log.Error("Reading raw body failed: ", err)
+			return r.fail(c, OperationCreate, http.StatusBadRequest, ErrorInvalidData)
+		}
 	} else {
 		// Check that we have a bind type set up already. If not, we must fail the call.
 		if r.createBindType == nil {
 			log.Error("Cannot write without a bind type set up. Call SetCreateBindType.")
-			return res.FailCode(c, http.StatusInternalServerError, ErrorNoBindType)
+			return r.fail(c, OperationCreate, http.StatusInternalServerError, ErrorNoBindType)
 		}
 
 		// Try to instantiate the "DTO" type, and bind to it.
@@ -284,20 +778,120 @@ func (r *Resource[T]) create(c echo.Context) error {
 		bound := boundPtr.Interface()
 		if err := c.Bind(bound); err != nil {
 			log.Error("Binding failed: ", err)
-			return res.FailCode(c, http.StatusBadRequest, ErrorInvalidData)
+			return r.fail(c, OperationCreate, http.StatusBadRequest, bindError(err))
 		}
 
-		_, err := patch.Struct(&model, bound)
-		if err != nil {
+		if err := r.validate(bound); err != nil {
+			return r.fail(c, OperationCreate, http.StatusBadRequest, err)
+		}
+
+		if err := mergeStruct(&model, bound, r.ignorePatchMismatches); err != nil {
 			log.Error("Patching failed: ", err)
-			return res.FailCode(c, http.StatusBadRequest, ErrorInvalidData)
+			return r.fail(c, OperationCreate, http.StatusBadRequest, err)
+		}
+	}
+
+	// Generate the key up front, so it's known before the database responds.
+	if r.idGenerator != nil {
+		if err := generateID(r.query(c), &model, r.idGenerator); err != nil {
+			log.Errorf("Could not generate id for resource %s: %s", reflect.TypeOf(r), err)
+			return r.fail(c, OperationCreate, http.StatusInternalServerError, ErrorInvalidID)
+		}
+	}
+
+	// Hand the entity off to be created in the background, unless dry running which must not have side effects.
+	if r.asyncCreate != nil && !isDryRun(c) {
+		if r.beforeCreate != nil {
+			if err := r.beforeCreate(c, &model); err != nil {
+				return r.fail(c, OperationCreate, http.StatusBadRequest, err)
+			}
+		}
+
+		statusURL, err := r.asyncCreate(c, &model)
+		if err != nil {
+			log.Errorf("Could not queue create for resource %s: %s", reflect.TypeOf(r), err)
+			return r.fail(c, OperationCreate, http.StatusInternalServerError, ErrorAsyncCreate)
+		}
+
+		c.Response().Header().Set(echo.HeaderLocation, statusURL)
+		return res.Accepted(c, AcceptedResponse{StatusURL: statusURL})
+	}
+
+	// Finally create, or only pretend to when dry running so the client gets validation feedback.
+	var beforeErr, hookErr error
+	create := func(q *gorm.DB) error {
+		if r.beforeCreate != nil {
+			if beforeErr = r.beforeCreate(c, &model); beforeErr != nil {
+				return beforeErr
+			}
+		}
+
+		// Give a friendly error for composite unique constraints, rather than relying on the database error.
+		if err := checkUnique(q, &model, r.uniqueSets); err != nil {
+			return err
+		}
+
+		if err := r.createQuery(c, q, &model); err != nil {
+			return err
+		}
+
+		// The after hook may have side effects, so it doesn't run when dry running.
+		if r.afterCreate != nil && !isDryRun(c) {
+			hookErr = r.afterCreate(c, &model)
+		}
+
+		return hookErr
+	}
+
+	// The hooks, the unique check and the insert share a transaction, so failing a hook rolls everything back.
+	var err error
+	if isDryRun(c) {
+		err = dryRun(r.query(c), create)
+	} else {
+		err = r.query(c).Transaction(create)
+	}
+
+	if err != nil {
+		if beforeErr != nil {
+			return r.fail(c, OperationCreate, http.StatusBadRequest, beforeErr)
+		}
+
+		if errors.Is(err, ErrorConflict) {
+			return r.fail(c, OperationCreate, http.StatusConflict, err)
+		}
+
+		if hookErr != nil {
+			log.Errorf("After create hook failed for resource %s: %s", reflect.TypeOf(r), hookErr)
+			return r.fail(c, OperationCreate, http.StatusInternalServerError, hookErr)
+		}
+
+		log.Errorf("Could not create for resource %s: %s", reflect.TypeOf(r), err)
+		return r.fail(c, OperationCreate, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	// Respond with what would have been saved.
+	if isDryRun(c) {
+		item, err := transformed(r, c, &model, r.getTransformer)
+		if err != nil {
+			return r.failTransform(c, OperationCreate, err)
 		}
+
+		return res.Ok(c, item)
+	}
+
+	// Point the client at where the new entity lives, using the same column as get by id looks it up by.
+	if id, ok := columnValue(r.query(c), &model, r.idColumn()); ok {
+		location := fmt.Sprintf("%s/%v", strings.TrimSuffix(c.Request().URL.Path, "/"), id)
+		c.Response().Header().Set(echo.HeaderLocation, ExternalURL(c, location))
 	}
 
-	// Finally create.
-	tx := database.Db.Create(&model)
-	if tx.Error != nil {
-		return res.FailCode(c, http.StatusInternalServerError, ErrorDatabase)
+	if r.returnCreatedEntity {
+		item, err := transformed(r, c, &model, r.getTransformer)
+		if err != nil {
+			return r.failTransform(c, OperationCreate, err)
+		}
+
+		return res.Created(c, item)
 	}
 
 	return c.NoContent(http.StatusOK)
@@ -305,48 +899,265 @@ func (r *Resource[T]) create(c echo.Context) error {
 
 func (r *Resource[T]) deleteById(c echo.Context) error {
 	// Parse the ID parameter, or fail.
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := r.parseID(c)
 	if err != nil {
-		return res.FailCode(c, http.StatusBadRequest, ErrorInvalidID)
+		return r.fail(c, OperationDeleteById, http.StatusBadRequest, err)
 	}
 
+	// Load the entity the same way getById does, so the same access rules apply before deleting. The lookup, the
+	// access check and the delete share a transaction, so a denied or failed step leaves nothing behind. The row is
+	// locked from the lookup on, so concurrent writes wait for the delete.
 	var result T
-	tx := database.Db.First(&result, "id = ?", id)
-	if tx.Error != nil {
-		err = tx.Error
-	}
+	err = r.query(c).Transaction(func(tx *gorm.DB) error {
+		q := r.scoped(c, tx)
+		entity, err := r.listByIdQuery(c, forUpdate(q), id)
+		if err != nil {
+			return err
+		}
+		result = *entity
 
-	if r.canDeleteById != nil {
-		if !r.canDeleteById(c, result) {
-			return ErrorNoResourceAccess
+		if r.canDeleteById != nil {
+			if !r.canDeleteById(c, result) {
+				return ErrorNoResourceAccess
+			}
 		}
-	}
 
-	err = r.deleteByIdQuery(c, database.Db, result)
+		return r.deleteByIdQuery(c, q, result)
+	})
+
 	if err != nil {
 		// Tried to delete a non existant entity.
 		if errors.Is(err, ErrorNoResourceFound) {
-			return res.FailCode(c, http.StatusNotFound, ErrorNoResourceFound)
+			return r.fail(c, OperationDeleteById, http.StatusNotFound, ErrorNoResourceFound)
 		}
 
 		// When we don't have access to the resource.
 		if errors.Is(err, ErrorNoResourceAccess) {
-			return res.FailCode(c, http.StatusForbidden, ErrorNoResourceAccess)
+			return r.fail(c, OperationDeleteById, http.StatusForbidden, ErrorNoResourceAccess)
 		}
 
 		// Otherwise, send them a 500.
 		log.Errorf("Could not delete by id for resource %s: %s", reflect.TypeOf(r), err)
-		return res.FailCode(c, http.StatusInternalServerError, ErrorDatabase)
+		return r.fail(c, OperationDeleteById, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	// Echo the entity back so clients can offer to undo the deletion.
+	if r.returnDeleted {
+		item, err := transformed(r, c, &result, r.getTransformer)
+		if err != nil {
+			return r.failTransform(c, OperationDeleteById, err)
+		}
+
+		return res.Ok(c, item)
 	}
 
 	return c.NoContent(http.StatusOK)
 }
 
+// query returns the database bound to the request context, so that queries are cancelled along with the request,
+// e.g. when the client disconnects or the request times out.
+func (r *Resource[T]) query(c echo.Context) *gorm.DB {
+	return r.database().WithContext(c.Request().Context())
+}
+
+// database returns the database of the resource, falling back to the global database.
+func (r *Resource[T]) database() *gorm.DB {
+	if r.db != nil {
+		return r.db
+	}
+
+	return database.Db
+}
+
+// ready reports whether the database of the resource can be queried. Only the global database is health checked.
+func (r *Resource[T]) ready() bool {
+	if r.db != nil && r.db != database.Db {
+		return true
+	}
+
+	return database.Ready()
+}
+
+// readQuery returns the scoped query used for reads. Soft-deleted records are included when ?with_deleted=true is passed,
+// which is only allowed when the CanViewDeleted predicate says so.
+func (r *Resource[T]) readQuery(c echo.Context) (*gorm.DB, error) {
+	if r.includeSoftDeleted {
+		return r.scoped(c, r.query(c).Unscoped()), nil
+	}
+
+	if c.QueryParam("with_deleted") != "true" {
+		return r.scoped(c, r.query(c)), nil
+	}
+
+	if r.canViewDeleted == nil || !r.canViewDeleted(c) {
+		return nil, ErrorNoResourceAccess
+	}
+
+	return r.scoped(c, r.query(c).Unscoped()), nil
+}
+
+// scoped restricts q to the records within the scope of the request, if a scope is set.
+func (r *Resource[T]) scoped(c echo.Context, q *gorm.DB) *gorm.DB {
+	if r.scope == nil {
+		return q
+	}
+
+	// A new session, so the query can be reused without conditions leaking between statements.
+	return r.scope(c, q).Session(&gorm.Session{})
+}
+
+// parseID parses the :id path parameter using the id parser, defaulting to unsigned integers.
+// The returned error wraps ErrorInvalidID and explains what was wrong.
+func (r *Resource[T]) parseID(c echo.Context) (any, error) {
+	parser := r.idParser
+	if parser == nil {
+		parser = parseUintID
+	}
+
+	id, err := parser(c.Param("id"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrorInvalidID, err)
+	}
+
+	return id, nil
+}
+
+// parseUintID parses s as an uint. Negative values are rejected rather than wrapped around, and values not fitting an
+// uint on this platform are rejected as out of range.
+func parseUintID(s string) (any, error) {
+	id, err := strconv.ParseUint(s, 10, strconv.IntSize)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return nil, errors.New("out of range")
+		}
+
+		return nil, errors.New("expected a non-negative integer")
+	}
+
+	return uint(id), nil
+}
+
+// ParseUUID is an id parser for resources keyed by UUIDs, for use with SetIDParser. It accepts the canonical
+// 8-4-4-4-12 hex form and returns it lowercased as a string, which both uuid columns and string fields accept.
+func ParseUUID(s string) (any, error) {
+	if len(s) != 36 {
+		return nil, errors.New("expected a UUID")
+	}
+
+	for i, ch := range s {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if ch != '-' {
+				return nil, errors.New("expected a UUID")
+			}
+		case !strings.ContainsRune("0123456789abcdefABCDEF", ch):
+			return nil, errors.New("expected a UUID")
+		}
+	}
+
+	return strings.ToLower(s), nil
+}
+
+// present prepares the result for serialization, applying field visibility and then the response decorator if they
+// have been set up. v is either a T, a *T or a []T.
+func (r *Resource[T]) present(c echo.Context, v any) any {
+	selected := r.selectedFields(c)
+	if r.fieldVisibility == nil && r.responseDecorator == nil && selected == nil {
+		return v
+	}
+
+	switch v := v.(type) {
+	case []T:
+		if v == nil {
+			return v
+		}
+
+		result := make([]any, len(v))
+		for i, entity := range v {
+			result[i] = r.presentEntity(c, entity, selected)
+		}
+		return result
+	case *T:
+		if v == nil {
+			return v
+		}
+		return r.presentEntity(c, *v, selected)
+	case T:
+		return r.presentEntity(c, v, selected)
+	}
+
+	return v
+}
+
+// presentEntity masks the fields of entity, leaving out those which aren't selected when selected is set.
+func (r *Resource[T]) presentEntity(c echo.Context, entity T, selected map[string]bool) any {
+	visible := r.fieldVisibility
+	if visible == nil {
+		if r.responseDecorator == nil && selected == nil {
+			return entity
+		}
+
+		visible = func(c echo.Context, field string) bool {
+			return true
+		}
+	}
+
+	result := maskFields(c, entity, func(c echo.Context, field string) bool {
+		return (selected == nil || selected[field]) && visible(c, field)
+	})
+	if fields, ok := result.(map[string]any); ok && r.responseDecorator != nil {
+		for k, v := range r.responseDecorator(c, entity) {
+			fields[k] = v
+		}
+	}
+
+	return result
+}
+
 func (r *Resource[T]) Middlewares(m ...echo.MiddlewareFunc) {
 	r.middlewares = m
 }
 
+// Deprecate adds the Deprecation header, and the Sunset and successor Link headers when given, to every response of
+// the resource. This is meant for old versions of a resource, e.g. one routed at /v1/users while /v2/users replaces it.
+func (r *Resource[T]) Deprecate(sunset time.Time, successor string) {
+	r.deprecation = Deprecated(sunset, successor)
+}
+
+// UseJWT requires every request to the resource to carry a valid HS256 signed bearer token, see JWT. Predicates and
+// hooks can then read its claims using Claims.
+func (r *Resource[T]) UseJWT(secret []byte, claimsFactory func() jwt.Claims) {
+	r.jwt = JWT(secret, claimsFactory)
+}
+
+// SetDB makes the resource query db, rather than the database of the server. This allows resources to live in
+// different databases.
+func (r *Resource[T]) SetDB(db *gorm.DB) {
+	r.db = db
+}
+
+func (r *Resource[T]) useDatabase(db *gorm.DB) {
+	if r.db == nil {
+		r.db = db
+	}
+}
+
+func (r *Resource[T]) inheritMiddlewares(m []echo.MiddlewareFunc) {
+	r.inheritedMiddlewares = m
+}
+
+// SetBasePath mounts the resource under prefix, e.g. "/api/v1" routes a resource named "/users" at /api/v1/users.
+// Takes precedence over Config.APIPrefix.
+func (r *Resource[T]) SetBasePath(prefix string) {
+	r.basePath = prefix
+}
+
+func (r *Resource[T]) inheritBasePath(prefix string) {
+	if r.basePath == "" {
+		r.basePath = prefix
+	}
+}
+
 // CanListAll takes a predicate and determines whether the operation can proceed.
 func (r *Resource[T]) CanListAll(predicate func(c echo.Context) bool) {
 	r.canListAll = predicate
@@ -367,16 +1178,61 @@ func (r *Resource[T]) CanDeleteById(predicate func(c echo.Context, entity T) boo
 	r.canDeleteById = predicate
 }
 
+// CanBulkWrite takes a predicate and determines whether the bulk write operation can proceed.
+func (r *Resource[T]) CanBulkWrite(predicate func(c echo.Context) bool) {
+	r.canBulkWrite = predicate
+}
+
+// CanViewDeleted takes a predicate and determines whether reads may include soft-deleted records.
+// Without it, ?with_deleted=true is always forbidden.
+func (r *Resource[T]) CanViewDeleted(predicate func(c echo.Context) bool) {
+	r.canViewDeleted = predicate
+}
+
 // OverrideListAllQuery lets consumers override the query used in the "List All" operation.
 func (r *Resource[T]) OverrideListAllQuery(predicate func(c echo.Context, q *gorm.DB) ([]T, error)) {
 	r.listAllQuery = predicate
 }
 
 // OverrideListByIdQuery lets consumers override the query used in the "List By Id" operation.
-func (r *Resource[T]) OverrideListByIdQuery(predicate func(c echo.Context, q *gorm.DB, id uint) (*T, error)) {
+func (r *Resource[T]) OverrideListByIdQuery(predicate func(c echo.Context, q *gorm.DB, id any) (*T, error)) {
 	r.listByIdQuery = predicate
 }
 
+/*
+SetOptimisticLockField makes writes fail with 409 Conflict when the entity was changed since the client loaded it.
+name is an integer field of T which every write bumps, such as Version. To detect concurrent edits, the write bind
+type should have the field too, so clients send back the version they loaded:
+
+	type Document struct {
+		ID      uint
+		Body    string
+		Version int
+	}
+
+	type DocumentUpdate struct {
+		Body    string
+		Version int
+	}
+
+Without it being sent, the version that was loaded during the write is expected instead.
+*/
+func (r *Resource[T]) SetOptimisticLockField(name string) {
+	r.optimisticLockField = name
+}
+
+// OverrideCreateQuery lets consumers override the query saving the entity in the "Create" operation, e.g. to upsert.
+func (r *Resource[T]) OverrideCreateQuery(predicate func(c echo.Context, q *gorm.DB, model *T) error) {
+	r.createQuery = predicate
+}
+
+// OverrideWriteByIdQuery lets consumers override the query used in the "Write By Id" operation. new points to the
+// bound write bind type, and the query is responsible for loading the entity, checking CanWriteById and saving it.
+// Returning ErrorNoResourceFound or ErrorNoResourceAccess responds with 404 or 403.
+func (r *Resource[T]) OverrideWriteByIdQuery(predicate func(c echo.Context, q *gorm.DB, id any, new any) error) {
+	r.writeByIdQuery = predicate
+}
+
 // OverrideDeleteByIdQuery lets consumers override the query used in the "Delete By Id" operation.
 func (r *Resource[T]) OverrideDeleteByIdQuery(predicate func(c echo.Context, q *gorm.DB, entity T) error) {
 	r.deleteByIdQuery = predicate
@@ -392,10 +1248,331 @@ func (r *Resource[T]) SetCreateBindType(t any) {
 	r.createBindType = t
 }
 
+// BeforeCreate sets a hook run on the entity right before it is created, e.g. to hash a password. It runs in the same
+// transaction as the insert and the after hook. Returning an error aborts the create with 400 Bad Request. The hook
+// also runs when dry running.
+func (r *Resource[T]) BeforeCreate(hook func(c echo.Context, model *T) error) {
+	r.beforeCreate = hook
+}
+
+// AfterCreate sets a hook run right after the entity has been inserted, e.g. to emit an event. The insert and the hook
+// share a transaction, so returning an error rolls the created row back and responds with 500. The hook doesn't run
+// when dry running.
+func (r *Resource[T]) AfterCreate(hook func(c echo.Context, model *T) error) {
+	r.afterCreate = hook
+}
+
+// SetCreateTransformer replaces binding on create. Errors it returns respond with 400, and errors implementing
+// FieldErrorer, such as FieldErrors, keep their messages per field in the response.
 func (r *Resource[T]) SetCreateTransformer(tf func(c echo.Context) (*T, error)) {
 	r.createTransformer = tf
 }
 
+/*
+SetListTransformer maps the entities listed by list all to what is responded with, e.g. DTOs leaving out secrets:
+
+	api.SetListTransformer(func(c echo.Context, users []User) (any, error) {
+		dtos := make([]UserDTO, len(users))
+		for i, u := range users {
+			dtos[i] = UserDTO{ID: u.ID, Name: u.Name}
+		}
+		return dtos, nil
+	})
+
+The result replaces the field visibility, response decoration and ?fields= masking of the entities, and becomes the
+Items of paginated responses. Returning ErrorNoResourceAccess responds with 403, and errors implementing FieldErrorer
+with 400. Other errors are logged, and respond with 500 without revealing them.
+*/
+func (r *Resource[T]) SetListTransformer(tf func(c echo.Context, items []T) (any, error)) {
+	r.listTransformer = tf
+}
+
+// SetGetTransformer maps the entity read by get by id to what is responded with, like SetListTransformer does for
+// list all. It also maps the entities responded with by create, write, delete and restore.
+func (r *Resource[T]) SetGetTransformer(tf func(c echo.Context, item *T) (any, error)) {
+	r.getTransformer = tf
+}
+
+// transformed maps what a read responds with using tf, or presents v when there is no transformer.
+func transformed[T, V any](r *Resource[T], c echo.Context, v V, tf func(echo.Context, V) (any, error)) (any, error) {
+	if tf == nil {
+		return r.present(c, v), nil
+	}
+
+	return tf(c, v)
+}
+
+// failTransform responds with the error of a list or get transformer.
+func (r *Resource[T]) failTransform(c echo.Context, op string, err error) error {
+	if errors.Is(err, ErrorNoResourceAccess) {
+		return r.fail(c, op, http.StatusForbidden, err)
+	}
+
+	var fieldErr FieldErrorer
+	if errors.As(err, &fieldErr) {
+		return r.fail(c, op, http.StatusBadRequest, err)
+	}
+
+	// Anything else may carry internals, so it is only logged.
+	log.Errorf("Could not transform %s for resource %s: %s", op, reflect.TypeOf(r), err)
+	return r.fail(c, op, http.StatusInternalServerError, ErrorDatabase)
+}
+
+// EnableExport registers GET /export on the resource, streaming every entity the list would contain as newline
+// delimited JSON. Entities are loaded batchSize at a time, which keeps memory bounded for large tables.
+func (r *Resource[T]) EnableExport(batchSize int) {
+	r.exportBatchSize = batchSize
+}
+
+// SetDownloadable registers GET /:id/:field for the given string or byte slice fields of T, serving the field as a
+// file. Range requests, ETags and content type detection are supported, and access is checked like when getting by id.
+func (r *Resource[T]) SetDownloadable(fields ...string) {
+	r.downloadFields = fields
+}
+
+// SetFilterableFields lets clients filter list all on the given columns, e.g. ?status=active&role=admin. Several
+// values for the same column, as in ?status=active&status=pending, match any of them. Other parameters are ignored.
+// The filters also apply to counting and exporting.
+func (r *Resource[T]) SetFilterableFields(fields ...string) {
+	r.filterableFields = fields
+}
+
+// SetSortableFields lets clients sort list all on the given columns, e.g. ?sort=name&order=desc. Sorting on any
+// other column responds with 400 Bad Request.
+func (r *Resource[T]) SetSortableFields(fields ...string) {
+	r.sortableFields = fields
+}
+
+// SetSearchableFields lets clients search list all using ?q=, which matches entities where any of the given text
+// columns contains the search, ignoring case, e.g. ?q=smith. The search also applies to counting.
+func (r *Resource[T]) SetSearchableFields(fields ...string) {
+	r.searchableFields = fields
+}
+
+// SetTimestampField sets the column ?updated_since= is compared against, e.g. "modified_at". Defaults to "updated_at",
+// as kept by gorm for models with an UpdatedAt field.
+func (r *Resource[T]) SetTimestampField(name string) {
+	r.timestampField = name
+}
+
+// SetSelectableFields lets clients restrict the fields in the responses of list all and get by id, e.g.
+// ?fields=id,name. Columns which aren't selectable are ignored. Entities are still loaded whole, so access checks and
+// writes always see every column.
+func (r *Resource[T]) SetSelectableFields(fields ...string) {
+	r.selectableFields = fields
+}
+
+/*
+SetPagination makes list all respond with a single page of entities, along with the total count, as a
+PaginatedResponse. The page is picked using the ?page= and ?per_page= query parameters, e.g.
+
+	GET /products?page=2&per_page=50
+
+Pages are numbered from 1. Missing or invalid parameters fall back to the first page of defaultPerPage entities, and
+per_page is capped at maxPerPage. The list all query is handed a query with the offset and limit applied.
+*/
+func (r *Resource[T]) SetPagination(defaultPerPage uint, maxPerPage uint) {
+	if defaultPerPage == 0 || defaultPerPage > maxPerPage {
+		defaultPerPage = maxPerPage
+	}
+
+	r.defaultPerPage = int(defaultPerPage)
+	r.maxPerPage = int(maxPerPage)
+}
+
+/*
+SetCursorPagination makes list all respond with a page of entities ordered by field, such as "id", as a
+CursorResponse. The next page is requested by passing its NextCursor as ?after=, e.g.
+
+	GET /events?after=MTIz
+
+Unlike pages by number, entities inserted or deleted while paging don't shift the following pages, which suits feeds
+and infinite scrolling. The field has to be unique, and should increase for new entities. Pages hold ?per_page=
+entities as limited by SetPagination, or 100 without it, and never more than Config.MaxListSize. Sorting using ?sort=
+isn't possible.
+*/
+func (r *Resource[T]) SetCursorPagination(field string) {
+	r.cursorField = field
+}
+
+/*
+SetScope restricts every query of the resource to the records the request may see, by adding conditions to the
+query. Unlike the CanX predicates, which run after the record is loaded, records outside of the scope simply aren't
+found, so getting, writing or deleting them responds with 404 without leaking their existence:
+
+	api.SetScope(func(c echo.Context, q *gorm.DB) *gorm.DB {
+		user, _ := minimal.Principal[*User](c)
+		return q.Where("owner_id = ?", user.ID)
+	})
+*/
+func (r *Resource[T]) SetScope(scope func(c echo.Context, q *gorm.DB) *gorm.DB) {
+	r.scope = scope
+}
+
+/*
+SetBulkWritable enables PATCH on the collection, which applies one update to every row matching the filter:
+
+	PATCH /products?filter[category]=toys
+	{"discount": 10}
+
+Only the columns in writable may be updated, and only the columns in filterable may be filtered on.
+The response contains the number of affected rows. Combine with CanBulkWrite to restrict who may do this.
+*/
+func (r *Resource[T]) SetBulkWritable(writable []string, filterable []string) {
+	r.bulkWritableFields = writable
+	r.bulkFilterFields = filterable
+}
+
+/*
+EnableBulkCreate registers POST /bulk on the collection, which creates every entity in a JSON array of the create
+bind type, e.g. for imports:
+
+	POST /products/bulk
+	[{"Name": "Ball"}, {"Name": "Kite"}]
+
+The entities are inserted in a single transaction, so one failing rolls back the whole batch. With ?partial=true, or
+?atomic=false, the entities are created one by one instead, and the 207 Multi-Status response tells which of them
+failed. CanCreate and the create hooks apply to every entity, but create transformers and async create aren't used.
+*/
+func (r *Resource[T]) EnableBulkCreate() {
+	r.bulkCreateEnabled = true
+}
+
+/*
+SetPreloads sets the associations loaded by the default list and get queries:
+
+	api.SetPreloads(
+		minimal.PreloadSpec{Path: "Items", Args: []any{"active = ?", true}},
+		minimal.PreloadSpec{Path: "Order.Customer"},
+	)
+*/
+func (r *Resource[T]) SetPreloads(preloads ...PreloadSpec) {
+	r.preloads = preloads
+}
+
+/*
+Preload adds associations loaded by the default list and get queries, without conditions:
+
+	api.Preload("Author", "Comments")
+
+Clients may narrow the preloads down with ?include=Author, and including an association which isn't preloaded is a
+400 Bad Request.
+*/
+func (r *Resource[T]) Preload(associations ...string) {
+	for _, association := range associations {
+		r.preloads = append(r.preloads, PreloadSpec{Path: association})
+	}
+}
+
+// SetPrimaryKey sets the column the id in the path is matched against, e.g. "uuid" or "user_id". Defaults to "id".
+func (r *Resource[T]) SetPrimaryKey(column string) {
+	r.primaryKeyColumn = column
+}
+
+// byId matches the entity with the id from the path.
+func (r *Resource[T]) byId(id any) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: r.idColumn()}, Value: id}
+}
+
+// idColumn returns the column the id in the path is matched against.
+func (r *Resource[T]) idColumn() string {
+	if r.primaryKeyColumn == "" {
+		return "id"
+	}
+
+	return r.primaryKeyColumn
+}
+
+// SetIDGenerator makes create populate an empty primary key using generate, e.g. for ULIDs or snowflake IDs.
+// The value must be assignable to the primary key field.
+func (r *Resource[T]) SetIDGenerator(generate func() any) {
+	r.idGenerator = generate
+}
+
+// SetAsyncCreate replaces the synchronous insert of create with f, which should queue the work and return a URL the
+// client can follow its progress at. Create then responds with 202 Accepted, and the status URL in the Location header.
+func (r *Resource[T]) SetAsyncCreate(f func(c echo.Context, model *T) (statusURL string, err error)) {
+	r.asyncCreate = f
+}
+
+// SetRawBodyField makes create store the raw JSON request body in the named field of T, instead of binding it onto a
+// bind type. This suits schemaless ingest endpoints like webhook receivers. The field may be a string, a []byte or a
+// byte slice type implementing driver.Valuer, such as datatypes.JSON.
+func (r *Resource[T]) SetRawBodyField(field string) {
+	r.rawBodyField = field
+}
+
+// SetIgnorePatchMismatches makes create and write skip bound fields whose type can't be merged onto T, logging a
+// warning, instead of responding with 400 Bad Request naming the field.
+func (r *Resource[T]) SetIgnorePatchMismatches(ignore bool) {
+	r.ignorePatchMismatches = ignore
+}
+
+// AddUniqueTogether makes create respond with 409 when an entity with the same values for all of fields already
+// exists, e.g. AddUniqueTogether("TenantID", "Slug"). Can be called several times for several sets of fields.
+func (r *Resource[T]) AddUniqueTogether(fields ...string) {
+	r.uniqueSets = append(r.uniqueSets, fields)
+}
+
+// SetReturnCreatedEntity makes create respond with 201 Created and the created entity, including generated fields
+// such as the id and timestamps, instead of no content.
+func (r *Resource[T]) SetReturnCreatedEntity(enabled bool) {
+	r.returnCreatedEntity = enabled
+}
+
+// SetReturnUpdatedEntity makes write by id respond with the entity as reloaded after saving, instead of no content.
+func (r *Resource[T]) SetReturnUpdatedEntity(enabled bool) {
+	r.returnUpdatedEntity = enabled
+}
+
+// SetReturnDeleted makes the delete operation respond with the deleted entity instead of no content.
+func (r *Resource[T]) SetReturnDeleted(enabled bool) {
+	r.returnDeleted = enabled
+}
+
+// SetIncludeSoftDeleted makes reads always include soft-deleted records, as if ?with_deleted=true was passed.
+func (r *Resource[T]) SetIncludeSoftDeleted(include bool) {
+	r.includeSoftDeleted = include
+}
+
+// SetHardDelete makes deleting permanent, even for entities with a gorm.DeletedAt field. Otherwise such entities are
+// soft-deleted, and can be restored using POST /:id/restore by whoever may delete them.
+func (r *Resource[T]) SetHardDelete(hard bool) {
+	r.hardDelete = hard
+}
+
+// SetIDParser sets how the :id path parameter is parsed, e.g. into a UUID using ParseUUID, or a slug.
+// The parsed value is passed to the query functions. Returning an error responds with 400.
+func (r *Resource[T]) SetIDParser(parser func(s string) (any, error)) {
+	r.idParser = parser
+}
+
+// SetValidator sets the validator checking the validate struct tags of the bind types on create and write, e.g. one
+// with custom rules registered. Failing fields are listed in the 400 Bad Request response. Defaults to a shared
+// validator.
+func (r *Resource[T]) SetValidator(v *validator.Validate) {
+	r.validator = v
+}
+
+// SetFieldVisibility takes a predicate which decides, per request, whether a field is included in read responses.
+// The field argument is the JSON name of the field, so a role check can hide e.g. "email" from non-admins.
+func (r *Resource[T]) SetFieldVisibility(predicate func(c echo.Context, field string) bool) {
+	r.fieldVisibility = predicate
+}
+
+/*
+SetResponseDecorator adds computed fields, such as links, to every serialized entity in list and single responses:
+
+	api.SetResponseDecorator(func(c echo.Context, entity Test) map[string]any {
+		return map[string]any{"Self": fmt.Sprintf("/tests/%d", entity.ID)}
+	})
+
+It runs after field visibility has been applied, and its fields take precedence over those of the entity.
+*/
+func (r *Resource[T]) SetResponseDecorator(decorator func(c echo.Context, entity T) map[string]any) {
+	r.responseDecorator = decorator
+}
+
 // OnRegister sets the registration hook to argument f.
 func (r *Resource[T]) OnRegister(f func(e *echo.Echo)) {
 	r.onRegister = f
@@ -1,13 +1,32 @@
 package minimal
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/kaiaverkvist/minimal/database"
+	"github.com/kaiaverkvist/minimal/res"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 type TestData struct {
@@ -45,3 +64,2339 @@ func TestResource_OverrideListAllQuery(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Nil(t, b)
 }
+
+func TestResource_SetFieldVisibility(t *testing.T) {
+	type Inner struct {
+		ID uint
+	}
+	type Masked struct {
+		Inner
+		Name   string
+		Email  string `json:"email"`
+		Secret string `json:"-"`
+	}
+
+	api := Resource[Masked]{}
+	api.SetFieldVisibility(func(c echo.Context, field string) bool {
+		return field != "email"
+	})
+
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	single := api.present(c, &Masked{Inner: Inner{ID: 1}, Name: "a", Email: "b", Secret: "c"})
+	assert.Equal(t, map[string]any{"ID": uint(1), "Name": "a"}, single)
+
+	list := api.present(c, []Masked{{Name: "a", Email: "b"}})
+	assert.Equal(t, []any{map[string]any{"ID": uint(0), "Name": "a"}}, list)
+}
+
+func TestResource_Options(t *testing.T) {
+	api := TestResource{Resource[TestData]{Name: "/tests"}}
+
+	e := echo.New()
+	api.Register(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/tests", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "GET, HEAD, POST, OPTIONS", rec.Header().Get(echo.HeaderAllow))
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/tests/1", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "GET, PUT, PATCH, DELETE, OPTIONS", rec.Header().Get(echo.HeaderAllow))
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(TenantMiddleware(func(c echo.Context) (string, error) {
+		switch tenant := c.Request().Header.Get("X-Tenant"); tenant {
+		case "":
+			return "", ErrorInvalidTenant
+		case "blocked":
+			return "", ErrorNoTenantAccess
+		default:
+			return tenant, nil
+		}
+	}))
+	e.GET("/", func(c echo.Context) error {
+		return c.String(http.StatusOK, Tenant(c))
+	})
+
+	for header, code := range map[string]int{"": http.StatusBadRequest, "blocked": http.StatusUnauthorized, "acme": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant", header)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, code, rec.Code)
+		if code == http.StatusOK {
+			assert.Equal(t, header, rec.Body.String())
+		}
+	}
+}
+
+type TestUser struct {
+	ID uint
+}
+
+func TestPrincipal(t *testing.T) {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	_, ok := Principal[*TestUser](c)
+	assert.False(t, ok)
+
+	SetPrincipal(c, &TestUser{ID: 7})
+
+	user, ok := Principal[*TestUser](c)
+	assert.True(t, ok)
+	assert.Equal(t, uint(7), user.ID)
+
+	_, ok = Principal[TestUser](c)
+	assert.False(t, ok)
+}
+
+func TestSkip(t *testing.T) {
+	e := echo.New()
+	e.Use(Skip(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+	}, PublicRoutes("/healthz", "/status/:service")))
+
+	ok := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	e.GET("/healthz", ok)
+	e.GET("/status/:service", ok)
+	e.GET("/private", ok)
+
+	for target, code := range map[string]int{
+		"/healthz":     http.StatusOK,
+		"/status/db":   http.StatusOK,
+		"/private":     http.StatusUnauthorized,
+		"/healthz/sub": http.StatusUnauthorized,
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, code, rec.Code, target)
+	}
+}
+
+func TestGzip(t *testing.T) {
+	e := echo.New()
+	e.Use(Gzip(GzipConfig{MinLength: 100}))
+	e.GET("/large", func(c echo.Context) error {
+		return c.String(http.StatusOK, strings.Repeat("a", 200))
+	})
+	e.GET("/small", func(c echo.Context) error {
+		return c.String(http.StatusOK, "a")
+	})
+	e.GET("/image", func(c echo.Context) error {
+		return c.Blob(http.StatusOK, "image/png", make([]byte, 200))
+	})
+
+	for target, compressed := range map[string]bool{"/large": true, "/small": false, "/image": false} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code, target)
+		if !compressed {
+			assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding), target)
+			continue
+		}
+
+		assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+		r, err := gzip.NewReader(rec.Body)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(r)
+		assert.Equal(t, strings.Repeat("a", 200), string(body))
+	}
+}
+
+func TestResource_NotReady(t *testing.T) {
+	api := TestResource{Resource[TestData]{Name: "/tests"}}
+
+	e := echo.New()
+	api.Register(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tests", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get(echo.HeaderRetryAfter))
+}
+
+func TestBindTracked(t *testing.T) {
+	type Update struct {
+		Name  string
+		Email *string `json:"email"`
+		Age   int
+	}
+	type Entity struct {
+		Name  string
+		Email string
+		Age   int
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name": "", "email": null}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	bound := &Update{}
+	assert.Nil(t, bindTracked(c, bound))
+
+	fields, ok := presentFields(c)
+	assert.True(t, ok)
+	assert.ElementsMatch(t, []string{"Name", "Email"}, fields)
+
+	entity := Entity{Name: "a", Email: "b", Age: 3}
+	updates, err := applyFields(&entity, bound, fields, false)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"Name": "", "Email": ""}, updates)
+	assert.Equal(t, Entity{Age: 3}, entity)
+}
+
+func TestResource_SetIDParser(t *testing.T) {
+	api := TestResource{Resource[TestData]{}}
+
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	c.SetParamNames("id")
+
+	c.SetParamValues("12")
+	id, err := api.parseID(c)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(12), id)
+
+	c.SetParamValues("-1")
+	_, err = api.parseID(c)
+	assert.ErrorIs(t, err, ErrorInvalidID)
+	assert.Equal(t, "bad id: expected a non-negative integer", err.Error())
+
+	c.SetParamValues("99999999999999999999999")
+	_, err = api.parseID(c)
+	assert.ErrorIs(t, err, ErrorInvalidID)
+	assert.Equal(t, "bad id: out of range", err.Error())
+
+	api.SetIDParser(func(s string) (any, error) {
+		return "slug-" + s, nil
+	})
+	c.SetParamValues("abc")
+	id, err = api.parseID(c)
+	assert.Nil(t, err)
+	assert.Equal(t, "slug-abc", id)
+}
+
+func TestParseUUID(t *testing.T) {
+	id, err := ParseUUID("6BA7B810-9DAD-11D1-80B4-00C04FD430C8")
+	assert.Nil(t, err)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", id)
+
+	for _, s := range []string{"12", "6ba7b810-9dad-11d1-80b4-00c04fd430cz", "6ba7b8109dad-11d1-80b4-00c04fd430c8-"} {
+		_, err = ParseUUID(s)
+		assert.Error(t, err, s)
+	}
+}
+
+type TestModel struct {
+	ID   uint
+	Name string
+}
+
+type TestModelUpdate struct {
+	Name string
+}
+
+func newTestModelServer(t *testing.T) (*Server, *Resource[TestModel]) {
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.SetWriteBindType(TestModelUpdate{})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	return s, api
+}
+
+// resetDatabase uninitializes the global database once the test is done, so tests don't leak into each other.
+func resetDatabase(t *testing.T) {
+	t.Cleanup(func() {
+		database.Db = nil
+		database.IsInitialized = false
+	})
+}
+
+func serve(s *Server, method string, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNewTestServer(t *testing.T) {
+	s, _ := newTestModelServer(t)
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodPut, "/models/1", `{"Name": "second"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodGet, "/models/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Name":"second"`)
+
+	rec = serve(s, http.MethodDelete, "/models/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodGet, "/models/1", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestResource_MalformedJSON(t *testing.T) {
+	s, _ := newTestModelServer(t)
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": }`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "malformed JSON at offset 10")
+
+	rec = serve(s, http.MethodPost, "/models", `{"Name": `)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "unexpected end of input")
+
+	rec = serve(s, http.MethodPost, "/models", `{"Name": 12}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "field Name should be string, got number")
+}
+
+func TestResource_BulkWrite(t *testing.T) {
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.SetBulkWritable([]string{"name"}, []string{"name"})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	for _, name := range []string{"a", "b", "c"} {
+		serve(s, http.MethodPost, "/models", `{"Name": "`+name+`"}`)
+	}
+
+	rec := serve(s, http.MethodPatch, "/models", `{"name": "z"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(s, http.MethodPatch, "/models?filter[id]=1", `{"name": "z"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(s, http.MethodPatch, "/models?filter[name]=a&filter[name]=b", `{"id": 5}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(s, http.MethodPatch, "/models?filter[name]=a&filter[name]=b", `{"name": "z"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"RowsAffected":2`)
+}
+
+func TestResource_SetReturnDeleted(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetReturnDeleted(true)
+
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+
+	rec := serve(s, http.MethodDelete, "/models/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Name":"first"`)
+}
+
+type TestSoftModel struct {
+	gorm.Model
+	Name string
+}
+
+func TestResource_CanViewDeleted(t *testing.T) {
+	api := &Resource[TestSoftModel]{Name: "/soft"}
+	api.SetCreateBindType(TestModelUpdate{})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/soft", `{"Name": "first"}`)
+	serve(s, http.MethodDelete, "/soft/1", "")
+
+	rec := serve(s, http.MethodGet, "/soft/1", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(s, http.MethodGet, "/soft/1?with_deleted=true", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	api.CanViewDeleted(func(c echo.Context) bool {
+		return true
+	})
+
+	rec = serve(s, http.MethodGet, "/soft/1?with_deleted=true", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodGet, "/soft?with_deleted=true", "")
+	assert.Contains(t, rec.Body.String(), `"Name":"first"`)
+}
+
+func TestRequireContentType(t *testing.T) {
+	e := echo.New()
+	e.Use(RequireContentType())
+	e.Any("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		method      string
+		contentType string
+		code        int
+	}{
+		{http.MethodGet, "", http.StatusOK},
+		{http.MethodPost, "", http.StatusUnsupportedMediaType},
+		{http.MethodPut, echo.MIMEApplicationForm, http.StatusUnsupportedMediaType},
+		{http.MethodPatch, echo.MIMEApplicationJSONCharsetUTF8, http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, "/", nil)
+		req.Header.Set(echo.HeaderContentType, tc.contentType)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, tc.code, rec.Code)
+	}
+}
+
+type TestItem struct {
+	ID      uint
+	OwnerID uint
+	Active  bool
+}
+
+type TestOwner struct {
+	ID    uint
+	Items []TestItem `gorm:"foreignKey:OwnerID"`
+}
+
+func TestResource_SetPreloads(t *testing.T) {
+	api := &Resource[TestOwner]{Name: "/owners"}
+	api.SetPreloads(PreloadSpec{Path: "Items", Args: []any{"active = ?", true}})
+
+	s := NewTestServer([]Provider{api}, []any{&TestItem{}})
+	resetDatabase(t)
+
+	database.Db.Create(&TestOwner{Items: []TestItem{{Active: true}, {Active: false}}})
+
+	rec := serve(s, http.MethodGet, "/owners/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Items":[{"ID":1,"OwnerID":1,"Active":true}]`)
+}
+
+func TestResource_PreloadInclude(t *testing.T) {
+	api := &Resource[TestOwner]{Name: "/owners"}
+	api.Preload("Items")
+
+	s := NewTestServer([]Provider{api}, []any{&TestItem{}})
+	resetDatabase(t)
+
+	database.Db.Create(&TestOwner{Items: []TestItem{{Active: true}}})
+
+	rec := serve(s, http.MethodGet, "/owners", "")
+	assert.Contains(t, rec.Body.String(), `"Items":[{"ID":1,"OwnerID":1,"Active":true}]`)
+
+	rec = serve(s, http.MethodGet, "/owners/1?include=", "")
+	assert.Contains(t, rec.Body.String(), `"Items":null`)
+
+	rec = serve(s, http.MethodGet, "/owners/1?include=Items", "")
+	assert.Contains(t, rec.Body.String(), `"Items":[{"ID":1,"OwnerID":1,"Active":true}]`)
+
+	rec = serve(s, http.MethodGet, "/owners?include=Secrets", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestResource_OnError(t *testing.T) {
+	s, api := newTestModelServer(t)
+
+	var ops []string
+	api.OnError(func(c echo.Context, op string, err error) error {
+		ops = append(ops, op)
+		if op == OperationGetById {
+			return c.String(http.StatusTeapot, err.Error())
+		}
+
+		return nil
+	})
+
+	rec := serve(s, http.MethodGet, "/models/1", "")
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, ErrorNoResourceFound.Error(), rec.Body.String())
+
+	rec = serve(s, http.MethodPut, "/models/x", `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []string{OperationGetById, OperationWriteById}, ops)
+}
+
+func TestResource_DeleteUsesListByIdQuery(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.CanListById(func(c echo.Context, entity TestModel) bool {
+		return entity.Name != "hidden"
+	})
+
+	serve(s, http.MethodPost, "/models", `{"Name": "hidden"}`)
+
+	rec := serve(s, http.MethodDelete, "/models/1", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = serve(s, http.MethodDelete, "/models/2", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRedactBody(t *testing.T) {
+	fields := append(defaultRedactedFields, "ssn")
+
+	out := redactBody(echo.MIMEApplicationJSONCharsetUTF8, []byte(`{"Name":"a","Password":"b","nested":[{"ssn":"c"}]}`), fields, 100)
+	assert.Equal(t, `{"Name":"a","Password":"[REDACTED]","nested":[{"ssn":"[REDACTED]"}]}`, out)
+
+	out = redactBody(echo.MIMEApplicationForm, []byte(`name=a&token=b`), fields, 100)
+	assert.Equal(t, `name=a&token=%5BREDACTED%5D`, out)
+
+	out = redactBody(echo.MIMETextPlain, []byte(`password=b`), fields, 100)
+	assert.Equal(t, `<text/plain body omitted>`, out)
+
+	out = redactBody(echo.MIMEApplicationJSON, []byte(`{"Name":"abcdef"}`), fields, 5)
+	assert.Equal(t, `{"Nam...(truncated)`, out)
+
+	// Fields merely containing a secret looking name are redacted too.
+	out = redactBody(echo.MIMEApplicationJSON,
+		[]byte(`{"client_secret":"a","newPassword":"b","Refresh-Token":"c","API_KEY":"d","name":"e"}`), fields, 200)
+	assert.Equal(t, `{"API_KEY":"[REDACTED]","Refresh-Token":"[REDACTED]","client_secret":"[REDACTED]",`+
+		`"name":"e","newPassword":"[REDACTED]"}`, out)
+}
+
+func TestBodyLogging(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stdout)
+	})
+
+	e := echo.New()
+	e.Use(BodyLogging(BodyLogConfig{MaxBufferBytes: 64}))
+	e.POST("/echo", func(c echo.Context) error {
+		body, _ := io.ReadAll(c.Request().Body)
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, body)
+	})
+
+	rec := serve(&Server{e: e}, http.MethodPost, "/echo", `{"api_key":"hidden","name":"shown"}`)
+	assert.Equal(t, `{"api_key":"hidden","name":"shown"}`, rec.Body.String())
+	assert.Contains(t, logged.String(), `{\"api_key\":\"[REDACTED]\",\"name\":\"shown\"}`)
+	assert.NotContains(t, logged.String(), "hidden")
+
+	large := fmt.Sprintf(`{"name":%q}`, strings.Repeat("a", 100))
+	rec = serve(&Server{e: e}, http.MethodPost, "/echo", large)
+	assert.Equal(t, large, rec.Body.String())
+	assert.Contains(t, logged.String(), "<body of more than 64 bytes omitted>")
+	assert.NotContains(t, logged.String(), strings.Repeat("a", 100))
+}
+
+func TestResource_CreateLocation(t *testing.T) {
+	s, _ := newTestModelServer(t)
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.Equal(t, "http://example.com/models/1", rec.Header().Get(echo.HeaderLocation))
+
+	s.Echo().Use(BaseURL("https://example.org/api/"))
+
+	rec = serve(s, http.MethodPost, "/models", `{"Name": "second"}`)
+	assert.Equal(t, "https://example.org/api/models/2", rec.Header().Get(echo.HeaderLocation))
+}
+
+func TestResource_MaxListSize(t *testing.T) {
+	s, _ := newTestModelServer(t)
+	s.Echo().Use(MaxListSize(2))
+
+	for _, name := range []string{"a", "b", "c"} {
+		serve(s, http.MethodPost, "/models", `{"Name": "`+name+`"}`)
+	}
+
+	rec := serve(s, http.MethodGet, "/models", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `"Name":"c"`)
+	assert.Contains(t, rec.Body.String(), `"Name":"b"`)
+}
+
+type TestSlugModel struct {
+	ID     uint
+	Tenant string
+	Slug   string
+}
+
+func TestResource_AddUniqueTogether(t *testing.T) {
+	api := &Resource[TestSlugModel]{Name: "/slugs"}
+	api.SetCreateBindType(TestSlugModel{})
+	api.AddUniqueTogether("Tenant", "slug")
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodPost, "/slugs", `{"Tenant": "a", "Slug": "x"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodPost, "/slugs", `{"Tenant": "b", "Slug": "x"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodPost, "/slugs", `{"Tenant": "a", "Slug": "x"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Tenant, slug must be unique together")
+}
+
+func TestResource_EnableCache(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.EnableCache(time.Minute)
+
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+
+	rec := serve(s, http.MethodGet, "/models", "")
+	assert.Contains(t, rec.Body.String(), `"Name":"first"`)
+
+	// Changed behind the back of the resource, so the cached response is still served.
+	database.Db.Model(&TestModel{}).Where("id = ?", 1).Update("name", "changed")
+	rec = serve(s, http.MethodGet, "/models", "")
+	assert.Contains(t, rec.Body.String(), `"Name":"first"`)
+
+	// Mutations through the resource bust the cache.
+	serve(s, http.MethodPost, "/models", `{"Name": "second"}`)
+	rec = serve(s, http.MethodGet, "/models", "")
+	assert.Contains(t, rec.Body.String(), `"Name":"changed"`)
+	assert.Contains(t, rec.Body.String(), `"Name":"second"`)
+}
+
+func TestResource_EnableCacheScoped(t *testing.T) {
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.EnableCache(time.Minute)
+	api.SetScope(func(c echo.Context, q *gorm.DB) *gorm.DB {
+		return q.Where("name = ?", c.Request().Header.Get("X-Owner"))
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/models", `{"Name": "alice"}`)
+	serve(s, http.MethodPost, "/models", `{"Name": "bob"}`)
+
+	get := func(owner string) string {
+		req := httptest.NewRequest(http.MethodGet, "/models", nil)
+		req.Header.Set("X-Owner", owner)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	assert.Contains(t, get("alice"), `"Name":"alice"`)
+
+	// The response of another principal must not be served from the cache.
+	body := get("bob")
+	assert.Contains(t, body, `"Name":"bob"`)
+	assert.NotContains(t, body, `"Name":"alice"`)
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache()
+	cache.Set("a:1", []byte("1"), 0)
+	cache.Set("a:2", []byte("2"), time.Minute)
+	cache.Set("b:1", []byte("3"), time.Nanosecond)
+
+	v, ok := cache.Get("a:1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), v)
+
+	time.Sleep(time.Millisecond)
+	_, ok = cache.Get("b:1")
+	assert.False(t, ok)
+	assert.NotContains(t, cache.entries, "b:1")
+
+	// Expired entries which are never read again are swept by a later set.
+	cache.Set("c:1", []byte("4"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	cache.nextSweep = time.Time{}
+	cache.Set("d:1", []byte("5"), 0)
+	assert.NotContains(t, cache.entries, "c:1")
+
+	cache.DeletePrefix("a:")
+	_, ok = cache.Get("a:2")
+	assert.False(t, ok)
+}
+
+func TestRegisterMount(t *testing.T) {
+	e := echo.New()
+	registerMount(e, Mount{
+		Prefix:       "/assets/",
+		FS:           http.FS(fstest.MapFS{"app.js": {Data: []byte("console.log(1)")}}),
+		CacheControl: "public, max-age=60",
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "console.log(1)", rec.Body.String())
+	assert.Equal(t, "public, max-age=60", rec.Header().Get(echo.HeaderCacheControl))
+}
+
+func TestResource_RoutePath(t *testing.T) {
+	api := Resource[TestModel]{}
+	assert.Equal(t, "TestModel", api.displayName())
+	assert.Equal(t, "/testmodels", api.routePath())
+
+	api.Name = "/models"
+	assert.Equal(t, "/models", api.displayName())
+	assert.Equal(t, "/models", api.routePath())
+
+	api.Name = "Model"
+	api.RoutePath = "/v2/models"
+	assert.Equal(t, "Model", api.displayName())
+	assert.Equal(t, "/v2/models", api.routePath())
+}
+
+type TestWebhook struct {
+	ID      uint
+	Payload []byte
+}
+
+func TestResource_SetRawBodyField(t *testing.T) {
+	api := &Resource[TestWebhook]{Name: "/hooks"}
+	api.SetRawBodyField("Payload")
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodPost, "/hooks", `{"event": "ping", "data": [1, 2]}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodPost, "/hooks", `{"event": `)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var hook TestWebhook
+	database.Db.First(&hook)
+	assert.JSONEq(t, `{"event": "ping", "data": [1, 2]}`, string(hook.Payload))
+}
+
+func TestResource_DryRun(t *testing.T) {
+	s, _ := newTestModelServer(t)
+
+	rec := serve(s, http.MethodPost, "/models?dry_run=true", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Name":"first"`)
+
+	var count int64
+	database.Db.Model(&TestModel{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+
+	req := httptest.NewRequest(http.MethodPut, "/models/1", strings.NewReader(`{"Name": "second"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Dry-Run", "true")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Name":"second"`)
+
+	rec = serve(s, http.MethodGet, "/models/1", "")
+	assert.Contains(t, rec.Body.String(), `"Name":"first"`)
+}
+
+func TestResource_SetAsyncCreate(t *testing.T) {
+	s, api := newTestModelServer(t)
+
+	var queued []string
+	api.SetAsyncCreate(func(c echo.Context, model *TestModel) (string, error) {
+		queued = append(queued, model.Name)
+		return "/jobs/1", nil
+	})
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "/jobs/1", rec.Header().Get(echo.HeaderLocation))
+	assert.Contains(t, rec.Body.String(), `"StatusURL":"/jobs/1"`)
+	assert.Equal(t, []string{"first"}, queued)
+
+	var count int64
+	database.Db.Model(&TestModel{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestResource_SetResponseDecorator(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetFieldVisibility(func(c echo.Context, field string) bool {
+		return field != "Name"
+	})
+	api.SetResponseDecorator(func(c echo.Context, entity TestModel) map[string]any {
+		return map[string]any{"Self": fmt.Sprintf("/models/%d", entity.ID)}
+	})
+
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+
+	rec := serve(s, http.MethodGet, "/models", "")
+	assert.JSONEq(t, `{"Success":true,"Message":"","Data":[{"ID":1,"Self":"/models/1"}]}`, rec.Body.String())
+
+	rec = serve(s, http.MethodGet, "/models/1", "")
+	assert.JSONEq(t, `{"Success":true,"Message":"","Data":{"ID":1,"Self":"/models/1"}}`, rec.Body.String())
+}
+
+func TestConfig_ResourceMiddlewares(t *testing.T) {
+	var order []string
+	tag := func(name string) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				order = append(order, name)
+				return next(c)
+			}
+		}
+	}
+
+	api := &TestResource{Resource[TestData]{Name: "/tests"}}
+	api.Middlewares(tag("resource"))
+
+	config := DevelopmentConfig
+	config.ResourceMiddlewares = []echo.MiddlewareFunc{tag("server")}
+	s := New(config, []Provider{api}, []any{})
+	s.registerRoutes()
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tests", nil))
+	assert.Equal(t, []string{"server", "resource"}, order)
+}
+
+type countingSerializer struct {
+	echo.DefaultJSONSerializer
+	calls int
+}
+
+func (s *countingSerializer) Serialize(c echo.Context, i interface{}, indent string) error {
+	s.calls++
+	return s.DefaultJSONSerializer.Serialize(c, i, indent)
+}
+
+func TestConfig_RequestTimeout(t *testing.T) {
+	config := DevelopmentConfig
+	config.RequestTimeout = 10 * time.Millisecond
+	s := New(config, []Provider{}, []any{})
+	s.setup(nil)
+	s.e.GET("/slow", func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+
+	rec := serve(&s, http.MethodGet, "/slow", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestConfig_CORS(t *testing.T) {
+	preflight := func(config Config, origin string) *httptest.ResponseRecorder {
+		s := New(config, []Provider{}, []any{})
+		s.setup(nil)
+		s.e.GET("/things", func(c echo.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodOptions, "/things", nil)
+		req.Header.Set(echo.HeaderOrigin, origin)
+		req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodGet)
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := preflight(DevelopmentConfig, "http://localhost:3000")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "http://localhost:3000", rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	rec = preflight(DevelopmentConfig, "https://example.com")
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+
+	config := DevelopmentConfig
+	config.CORS = CORSConfig{}
+	rec = preflight(config, "http://localhost:3000")
+	assert.Empty(t, rec.Header().Get(echo.HeaderAccessControlAllowOrigin))
+}
+
+func TestConfig_RateLimit(t *testing.T) {
+	config := DevelopmentConfig
+	config.RateLimit = RateLimitConfig{RequestsPerSecond: 0.5, Burst: 2}
+	s := New(config, []Provider{}, []any{})
+	s.setup(nil)
+	s.e.GET("/things", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	assert.Equal(t, http.StatusOK, serve(&s, http.MethodGet, "/things", "").Code)
+	assert.Equal(t, http.StatusOK, serve(&s, http.MethodGet, "/things", "").Code)
+
+	rec := serve(&s, http.MethodGet, "/things", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"Message":"too many requests"`)
+
+	// A rate below one request per second still lets the first request through.
+	config.RateLimit = RateLimitConfig{RequestsPerSecond: 0.5}
+	s = New(config, []Provider{}, []any{})
+	s.setup(nil)
+	s.e.GET("/things", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	assert.Equal(t, http.StatusOK, serve(&s, http.MethodGet, "/things", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(&s, http.MethodGet, "/things", "").Code)
+}
+
+func TestConfig_Dialect(t *testing.T) {
+	assert.Equal(t, database.MySQL, database.DetectDialect("user:pass@tcp(localhost:3306)/app"))
+	assert.Equal(t, database.SQLite, database.DetectDialect("app.db"))
+	assert.Equal(t, database.Postgres, database.DetectDialect("host=localhost user=postgres dbname=app"))
+
+	config := DevelopmentConfig
+	config.DSN = "file:dialect?mode=memory&cache=shared"
+	s := New(config, []Provider{}, []any{&TestModel{}})
+	s.initDatabase()
+	resetDatabase(t)
+
+	assert.Equal(t, "sqlite", database.Db.Dialector.Name())
+	assert.True(t, database.Db.Migrator().HasTable(&TestModel{}))
+}
+
+func TestConfig_JSONSerializer(t *testing.T) {
+	serializer := &countingSerializer{}
+
+	config := DevelopmentConfig
+	config.JSONSerializer = serializer
+	s := New(config, []Provider{}, []any{})
+	s.setup(nil)
+	s.e.GET("/json", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, "x")
+	})
+
+	rec := serve(&s, http.MethodGet, "/json", "")
+	assert.Equal(t, `"x"`+"\n", rec.Body.String())
+	assert.Equal(t, 1, serializer.calls)
+}
+
+func TestConfig_DB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:configdb?mode=memory&cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+
+	config := DevelopmentConfig
+	config.DB = db
+	s := New(config, []Provider{api}, []any{})
+	s.initDatabase()
+	s.setup(nil)
+	resetDatabase(t)
+
+	assert.Same(t, db, database.Db)
+
+	rec := serve(&s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+type TestKeyed struct {
+	ID   string
+	Name string
+}
+
+func TestResource_SetIDGenerator(t *testing.T) {
+	api := &Resource[TestKeyed]{Name: "/keyed"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.SetIDParser(func(s string) (any, error) {
+		return s, nil
+	})
+	api.SetIDGenerator(func() any {
+		return "01ARZ3NDEKTSV4RRFFQ69G5FAV"
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodPost, "/keyed", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "http://example.com/keyed/01ARZ3NDEKTSV4RRFFQ69G5FAV", rec.Header().Get(echo.HeaderLocation))
+
+	rec = serve(s, http.MethodGet, "/keyed/01ARZ3NDEKTSV4RRFFQ69G5FAV", "")
+	assert.Contains(t, rec.Body.String(), `"Name":"first"`)
+}
+
+type TestModelMismatch struct {
+	Name int
+}
+
+func TestResource_SetIgnorePatchMismatches(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetCreateBindType(TestModelMismatch{})
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": 5}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "field Name can't be merged, int into string")
+
+	api.SetIgnorePatchMismatches(true)
+
+	rec = serve(s, http.MethodPost, "/models", `{"Name": 5}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestResource_GetCount(t *testing.T) {
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.SetFilterableFields("name")
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	serve(s, http.MethodPost, "/models", `{"Name": "second"}`)
+
+	rec := serve(s, http.MethodGet, "/models/count", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"Count":2}`)
+
+	rec = serve(s, http.MethodGet, "/models/count?name=second", "")
+	assert.Contains(t, rec.Body.String(), `"Data":{"Count":1}`)
+
+	api.CanListAll(func(c echo.Context) bool {
+		return false
+	})
+
+	rec = serve(s, http.MethodGet, "/models/count", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestResource_HeadCount(t *testing.T) {
+	s, api := newTestModelServer(t)
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	serve(s, http.MethodPost, "/models", `{"Name": "second"}`)
+
+	rec := serve(s, http.MethodHead, "/models", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get(HeaderTotalCount))
+	assert.Empty(t, rec.Body.String())
+
+	api.CanListAll(func(c echo.Context) bool {
+		return false
+	})
+
+	rec = serve(s, http.MethodHead, "/models", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestResource_EnableExport(t *testing.T) {
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.EnableExport(2)
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	for _, name := range []string{"first", "second", "third"} {
+		serve(s, http.MethodPost, "/models", `{"Name": "`+name+`"}`)
+	}
+
+	rec := serve(s, http.MethodGet, "/models/export", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, MIMEApplicationNDJSON, rec.Header().Get(echo.HeaderContentType))
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[2], `"Name":"third"`)
+}
+
+func TestResource_EmptyList(t *testing.T) {
+	s, api := newTestModelServer(t)
+
+	rec := serve(s, http.MethodGet, "/models", "")
+	assert.Contains(t, rec.Body.String(), `"Data":[]`)
+
+	api.OverrideListAllQuery(func(c echo.Context, q *gorm.DB) ([]TestModel, error) {
+		return nil, nil
+	})
+
+	rec = serve(s, http.MethodGet, "/models", "")
+	assert.Contains(t, rec.Body.String(), `"Data":[]`)
+}
+
+func TestResource_WriteMissing(t *testing.T) {
+	s, _ := newTestModelServer(t)
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		rec := serve(s, method, "/models/42", `{"Name": "ghost"}`)
+		assert.Equal(t, http.StatusNotFound, rec.Code, method)
+	}
+
+	rec := serve(s, http.MethodGet, "/models", "")
+	assert.Contains(t, rec.Body.String(), `"Data":[]`)
+}
+
+func TestResource_CreateTransformerFieldErrors(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetCreateTransformer(func(c echo.Context) (*TestModel, error) {
+		return nil, FieldErrors{"Name": "is already taken"}
+	})
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Message":"bad data: Name is already taken"`)
+	assert.Contains(t, rec.Body.String(), `"Data":{"Name":"is already taken"}`)
+}
+
+func TestResource_Deprecate(t *testing.T) {
+	v1 := &Resource[TestModel]{Name: "users", RoutePath: "/v1/users"}
+	v1.Deprecate(time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC), "/v2/users")
+	v2 := &Resource[TestModel]{Name: "users", RoutePath: "/v2/users"}
+
+	s := NewTestServer([]Provider{v1, v2}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodGet, "/v1/users", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Sat, 01 Jun 2030 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</v2/users>; rel="successor-version"`, rec.Header().Get("Link"))
+
+	rec = serve(s, http.MethodGet, "/v2/users", "")
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+}
+
+func TestResource_SetDownloadable(t *testing.T) {
+	api := &Resource[TestWebhook]{Name: "/webhooks"}
+	api.SetRawBodyField("Payload")
+	api.SetDownloadable("Payload")
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/webhooks", `{"event": "push"}`)
+
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/1/Payload", nil)
+	req.Header.Set("Range", "bytes=2-6")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "event", rec.Body.String())
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+
+	rec = serve(s, http.MethodGet, "/webhooks/1/ID", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+type TestIndexed struct {
+	ID   uint
+	Name string
+}
+
+func (TestIndexed) PostMigrate(db *gorm.DB) error {
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_test_indexeds_name ON test_indexeds (name)").Error
+}
+
+func TestAutoMigrate_PostMigrate(t *testing.T) {
+	NewTestServer([]Provider{}, []any{&TestIndexed{}})
+	resetDatabase(t)
+
+	assert.True(t, database.Db.Migrator().HasIndex(&TestIndexed{}, "idx_test_indexeds_name"))
+}
+
+func TestResource_SetScope(t *testing.T) {
+	s, api := newTestModelServer(t)
+	serve(s, http.MethodPost, "/models", `{"Name": "secret"}`)
+	serve(s, http.MethodPost, "/models", `{"Name": "public"}`)
+
+	api.SetScope(func(c echo.Context, q *gorm.DB) *gorm.DB {
+		return q.Where("name <> ?", "secret")
+	})
+
+	rec := serve(s, http.MethodGet, "/models", "")
+	assert.NotContains(t, rec.Body.String(), "secret")
+	assert.Contains(t, rec.Body.String(), "public")
+
+	rec = serve(s, http.MethodHead, "/models", "")
+	assert.Equal(t, "1", rec.Header().Get(HeaderTotalCount))
+
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodGet, "/models/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodPut, "/models/1", `{"Name": "x"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodDelete, "/models/1", "").Code)
+
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodPut, "/models/2", `{"Name": "renamed"}`).Code)
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodDelete, "/models/2", "").Code)
+
+	var count int64
+	database.Db.Model(&TestModel{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+type TestModelPage struct {
+	Items   []TestModel
+	Page    int
+	PerPage int
+	Total   int64
+}
+
+func TestResource_SetPagination(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetPagination(2, 3)
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		serve(s, http.MethodPost, "/models", `{"Name": "`+name+`"}`)
+	}
+
+	page := func(target string) res.ModelResponse[TestModelPage] {
+		var response res.ModelResponse[TestModelPage]
+		rec := serve(s, http.MethodGet, target, "")
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response
+	}
+
+	first := page("/models")
+	assert.Equal(t, int64(5), first.Data.Total)
+	assert.Equal(t, 1, first.Data.Page)
+	assert.Len(t, first.Data.Items, 2)
+
+	last := page("/models?page=2&per_page=100")
+	assert.Equal(t, 3, last.Data.PerPage)
+	assert.Len(t, last.Data.Items, 2)
+	assert.Equal(t, "d", last.Data.Items[0].Name)
+
+	invalid := page("/models?page=x&per_page=-1")
+	assert.Equal(t, 1, invalid.Data.Page)
+	assert.Equal(t, 2, invalid.Data.PerPage)
+}
+
+func TestResource_SetSortableFields(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetSortableFields("name")
+
+	for _, name := range []string{"b", "c", "a"} {
+		serve(s, http.MethodPost, "/models", `{"Name": "`+name+`"}`)
+	}
+
+	var response res.ModelResponse[[]TestModel]
+	rec := serve(s, http.MethodGet, "/models?sort=name&order=desc", "")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, []string{"c", "b", "a"}, []string{response.Data[0].Name, response.Data[1].Name, response.Data[2].Name})
+
+	assert.Equal(t, http.StatusBadRequest, serve(s, http.MethodGet, "/models?sort=id", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(s, http.MethodGet, "/models?sort=name&order=sideways", "").Code)
+}
+
+func TestResource_SetFilterableFields(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetFilterableFields("name")
+	api.SetPagination(10, 10)
+
+	for _, name := range []string{"a", "b", "c"} {
+		serve(s, http.MethodPost, "/models", `{"Name": "`+name+`"}`)
+	}
+
+	var response res.ModelResponse[TestModelPage]
+	rec := serve(s, http.MethodGet, "/models?name=a&name=c&unknown=x", "")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, int64(2), response.Data.Total)
+	assert.Len(t, response.Data.Items, 2)
+
+	rec = serve(s, http.MethodHead, "/models?name=b", "")
+	assert.Equal(t, "1", rec.Header().Get(HeaderTotalCount))
+}
+
+func TestResource_CreateHooks(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.BeforeCreate(func(c echo.Context, model *TestModel) error {
+		if model.Name == "" {
+			return errors.New("name is required")
+		}
+
+		model.Name = strings.ToUpper(model.Name)
+		return nil
+	})
+	api.AfterCreate(func(c echo.Context, model *TestModel) error {
+		if model.Name == "FAIL" {
+			return errors.New("could not emit event")
+		}
+
+		return nil
+	})
+
+	rec := serve(s, http.MethodPost, "/models", `{}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "name is required")
+
+	rec = serve(s, http.MethodPost, "/models", `{"Name": "fail"}`)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var models []TestModel
+	database.Db.Find(&models)
+	assert.Equal(t, []TestModel{{ID: 1, Name: "FIRST"}}, models)
+}
+
+func TestResource_Restore(t *testing.T) {
+	api := &Resource[TestSoftModel]{Name: "/soft"}
+	api.SetCreateBindType(TestModelUpdate{})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/soft", `{"Name": "first"}`)
+	serve(s, http.MethodDelete, "/soft/1", "")
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodGet, "/soft/1", "").Code)
+
+	api.SetIncludeSoftDeleted(true)
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodGet, "/soft/1", "").Code)
+	api.SetIncludeSoftDeleted(false)
+
+	rec := serve(s, http.MethodPost, "/soft/1/restore", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"DeletedAt":null`)
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodGet, "/soft/1", "").Code)
+
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodPost, "/soft/2/restore", "").Code)
+}
+
+func TestResource_SetHardDelete(t *testing.T) {
+	api := &Resource[TestSoftModel]{Name: "/soft"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.SetHardDelete(true)
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/soft", `{"Name": "first"}`)
+	serve(s, http.MethodDelete, "/soft/1", "")
+
+	var count int64
+	database.Db.Unscoped().Model(&TestSoftModel{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+	assert.NotEqual(t, http.StatusOK, serve(s, http.MethodPost, "/soft/1/restore", "").Code)
+}
+
+func TestResource_ReturnEntities(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetReturnCreatedEntity(true)
+	api.SetReturnUpdatedEntity(true)
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"ID":1,"Name":"first"}`)
+
+	rec = serve(s, http.MethodPut, "/models/1", `{"Name": "renamed"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"ID":1,"Name":"renamed"}`)
+}
+
+type TestProfile struct {
+	ID    uint
+	Name  string
+	Email string
+}
+
+type TestProfileUpdate struct {
+	Name  string
+	Email string
+}
+
+func TestResource_PutReplacesPatchMerges(t *testing.T) {
+	api := &Resource[TestProfile]{Name: "/profiles"}
+	api.SetCreateBindType(TestProfileUpdate{})
+	api.SetWriteBindType(TestProfileUpdate{})
+	api.SetReturnUpdatedEntity(true)
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/profiles", `{"Name": "a", "Email": "a@example.com"}`)
+
+	rec := serve(s, http.MethodPatch, "/profiles/1", `{"Name": "b"}`)
+	assert.Contains(t, rec.Body.String(), `"Data":{"ID":1,"Name":"b","Email":"a@example.com"}`)
+
+	rec = serve(s, http.MethodPut, "/profiles/1", `{"Name": "c"}`)
+	assert.Contains(t, rec.Body.String(), `"Data":{"ID":1,"Name":"c","Email":""}`)
+}
+
+func TestResource_QueriesUseRequestContext(t *testing.T) {
+	type key struct{}
+
+	var value any
+	api := &Resource[TestModel]{Name: "/models"}
+	api.Middlewares(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), key{}, "request")))
+			return next(c)
+		}
+	})
+	api.OverrideListAllQuery(func(c echo.Context, q *gorm.DB) ([]TestModel, error) {
+		value = q.Statement.Context.Value(key{})
+		return nil, nil
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodGet, "/models", "")
+	assert.Equal(t, "request", value)
+}
+
+func TestResource_SetDB(t *testing.T) {
+	other, err := gorm.Open(sqlite.Open("file:otherdb?mode=memory&cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	archive := &Resource[TestModel]{Name: "/archive"}
+	archive.SetCreateBindType(TestModelUpdate{})
+	archive.SetDB(other)
+	models := &Resource[TestModel]{Name: "/models"}
+
+	s := NewTestServer([]Provider{archive, models}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodPost, "/archive", `{"Name": "archived"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodGet, "/models", "")
+	assert.Contains(t, rec.Body.String(), `"Data":[]`)
+
+	var count int64
+	other.Model(&TestModel{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+	assert.Same(t, s.DB(), database.Db)
+}
+
+func TestConfig_HealthCheckInterval(t *testing.T) {
+	open := func(name string) (*gorm.DB, *sql.DB) {
+		db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{})
+		assert.NoError(t, err)
+		sqlDb, err := db.DB()
+		assert.NoError(t, err)
+		return db, sqlDb
+	}
+
+	db, sqlDb := open("healthy")
+	config := DevelopmentConfig
+	config.DB = db
+	config.HealthCheckInterval = time.Millisecond
+	config.HealthCheckFailures = 1
+	s := New(config, []Provider{}, []any{})
+	s.initDatabase()
+	resetDatabase(t)
+
+	// The server's own database is pinged, not whatever the global one has been replaced by.
+	closed, closedSqlDb := open("closed")
+	assert.NoError(t, closedSqlDb.Close())
+	database.Db = closed
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, database.Ready())
+
+	// Once shut down, failing pings can't be noticed anymore.
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.NoError(t, sqlDb.Close())
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, database.Ready())
+}
+
+func TestServer_EnableHealthCheck(t *testing.T) {
+	s := New(DevelopmentConfig, []Provider{}, []any{})
+	s.EnableHealthCheck("/healthz")
+
+	rec := serve(&s, http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Status":"degraded"`)
+
+	ts := NewTestServer([]Provider{}, []any{})
+	resetDatabase(t)
+	ts.EnableHealthCheck("/healthz")
+
+	rec = serve(ts, http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Status":"ok","Database":true,"Initialized":true`)
+
+	sqlDb, _ := ts.DB().DB()
+	sqlDb.Close()
+
+	rec = serve(ts, http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+type TestProfileValidated struct {
+	Name  string `validate:"required"`
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+func TestResource_Validation(t *testing.T) {
+	api := &Resource[TestProfile]{Name: "/profiles"}
+	api.SetCreateBindType(TestProfileValidated{})
+	api.SetWriteBindType(TestProfileValidated{})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodPost, "/profiles", `{"email": "nope"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"Name":"is required","email":"must satisfy email"}`)
+
+	rec = serve(s, http.MethodPost, "/profiles", `{"Name": "a", "email": "a@example.com"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodPut, "/profiles/1", `{"Name": ""}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"Name":"is required"}`)
+
+	// PATCH only validates the fields sent.
+	rec = serve(s, http.MethodPatch, "/profiles/1", `{"email": "b@example.com"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodPatch, "/profiles/1", `{"email": "nope"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"email":"must satisfy email"}`)
+
+	rec = serve(s, http.MethodPatch, "/profiles/1", `{"Name": ""}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"Name":"is required"}`)
+}
+
+func TestResource_SetSelectableFields(t *testing.T) {
+	api := &Resource[TestProfile]{Name: "/profiles"}
+	api.SetCreateBindType(TestProfileUpdate{})
+	api.SetSelectableFields("id", "name")
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/profiles", `{"Name": "a", "Email": "a@example.com"}`)
+
+	rec := serve(s, http.MethodGet, "/profiles?fields=name,email,unknown", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":[{"Name":"a"}]`)
+
+	rec = serve(s, http.MethodGet, "/profiles/1?fields=id,name", "")
+	assert.Contains(t, rec.Body.String(), `"Data":{"ID":1,"Name":"a"}`)
+
+	rec = serve(s, http.MethodGet, "/profiles/1", "")
+	assert.Contains(t, rec.Body.String(), `"Data":{"ID":1,"Name":"a","Email":"a@example.com"}`)
+}
+
+type TestClaims struct {
+	jwt.StandardClaims
+	Role string
+}
+
+func TestResource_UseJWT(t *testing.T) {
+	secret := []byte("secret")
+
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.UseJWT(secret, func() jwt.Claims { return &TestClaims{} })
+	api.CanListAll(func(c echo.Context) bool {
+		claims, ok := Claims[*TestClaims](c)
+		return ok && claims.Role == "admin"
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	request := func(method string, target string, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(`{"Name": "first"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if role != "" {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, TestClaims{Role: role}).SignedString(secret)
+			assert.Nil(t, err)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/models", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Message":"unauthorized"`)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/models", "user").Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/models", "user").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/models", "admin").Code)
+}
+
+func TestResource_EnableBulkCreate(t *testing.T) {
+	api := &Resource[TestProfile]{Name: "/profiles"}
+	api.SetCreateBindType(TestProfileValidated{})
+	api.EnableBulkCreate()
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodPost, "/profiles/bulk", `[{"Name": "a"}, {"Name": "b"}]`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"Created":2,"Failed":0}`)
+
+	rec = serve(s, http.MethodPost, "/profiles/bulk", `[{"Name": "c"}, {"email": "d@example.com"}]`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Message":"item 1: bad data: Name is required"`)
+
+	rec = serve(s, http.MethodPost, "/profiles/bulk?partial=true", `[{"Name": "c"}, {"email": "d@example.com"}]`)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Created":1,"Failed":1`)
+	assert.Contains(t, rec.Body.String(), `{"Index":1,"Success":false,"Error":"bad data: Name is required"}`)
+
+	var count int64
+	database.Db.Model(&TestProfile{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestResource_BulkCreateHooks(t *testing.T) {
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.EnableBulkCreate()
+	api.BeforeCreate(func(c echo.Context, model *TestModel) error {
+		if model.Name == "" {
+			return errors.New("name is required")
+		}
+
+		model.Name = strings.ToUpper(model.Name)
+		return nil
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodPost, "/models/bulk", `[{"Name": "a"}, {}]`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Message":"item 1: name is required"`)
+
+	rec = serve(s, http.MethodPost, "/models/bulk?partial=true", `[{"Name": "b"}, {}]`)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), `{"Index":1,"Success":false,"Error":"name is required"}`)
+
+	var models []TestModel
+	database.Db.Find(&models)
+	assert.Equal(t, []TestModel{{ID: 1, Name: "B"}}, models)
+}
+
+func TestResource_ErrorCodes(t *testing.T) {
+	s, _ := newTestModelServer(t)
+
+	rec := serve(s, http.MethodGet, "/models/1", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Code":"no_resource_found"`)
+
+	rec = serve(s, http.MethodGet, "/models/x", "")
+	assert.Contains(t, rec.Body.String(), `"Code":"invalid_id"`)
+
+	rec = serve(s, http.MethodPost, "/models", `{"Name": 1}`)
+	assert.Contains(t, rec.Body.String(), `"Code":"invalid_data"`)
+}
+
+func TestResource_OverrideCreateAndWriteQueries(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.OverrideCreateQuery(func(c echo.Context, q *gorm.DB, model *TestModel) error {
+		model.Name = strings.ToUpper(model.Name)
+		return q.Create(model).Error
+	})
+	api.OverrideWriteByIdQuery(func(c echo.Context, q *gorm.DB, id any, new any) error {
+		return q.Model(&TestModel{}).Where("id = ?", id).Update("name", new.(*TestModelUpdate).Name+"!").Error
+	})
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "http://example.com/models/1", rec.Header().Get(echo.HeaderLocation))
+
+	rec = serve(s, http.MethodGet, "/models/1", "")
+	assert.Contains(t, rec.Body.String(), `"Name":"FIRST"`)
+
+	serve(s, http.MethodPut, "/models/1", `{"Name": "second"}`)
+	rec = serve(s, http.MethodGet, "/models/1", "")
+	assert.Contains(t, rec.Body.String(), `"Name":"second!"`)
+}
+
+func TestResource_CanDeleteByIdDenied(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.CanDeleteById(func(c echo.Context, entity TestModel) bool {
+		return false
+	})
+
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+
+	rec := serve(s, http.MethodDelete, "/models/1", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.JSONEq(t, fmt.Sprintf(
+		`{"Success":false,"Message":"no resource access","Code":"no_resource_access","RequestID":%q,"Data":null}`,
+		rec.Header().Get(echo.HeaderXRequestID),
+	), rec.Body.String())
+
+	rec = serve(s, http.MethodGet, "/models/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestResource_WritesRollBackOnFailure(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.OverrideDeleteByIdQuery(func(c echo.Context, q *gorm.DB, entity TestModel) error {
+		if err := q.Delete(&entity).Error; err != nil {
+			return err
+		}
+
+		return errors.New("failed after deleting")
+	})
+	api.OverrideWriteByIdQuery(func(c echo.Context, q *gorm.DB, id any, new any) error {
+		if err := q.Model(&TestModel{}).Where("id = ?", id).Update("name", "changed").Error; err != nil {
+			return err
+		}
+
+		return ErrorNoResourceAccess
+	})
+
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+
+	assert.Equal(t, http.StatusInternalServerError, serve(s, http.MethodDelete, "/models/1", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve(s, http.MethodPut, "/models/1", `{"Name": "second"}`).Code)
+
+	rec := serve(s, http.MethodGet, "/models/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Name":"first"`)
+}
+
+type TestDocument struct {
+	ID      uint
+	Body    string
+	Version int
+}
+
+type TestDocumentUpdate struct {
+	Body    string
+	Version int
+}
+
+func TestResource_SetOptimisticLockField(t *testing.T) {
+	api := &Resource[TestDocument]{Name: "/documents"}
+	api.SetCreateBindType(TestDocumentUpdate{})
+	api.SetWriteBindType(TestDocumentUpdate{})
+	api.SetOptimisticLockField("Version")
+	api.SetReturnUpdatedEntity(true)
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/documents", `{"Body": "a"}`)
+
+	rec := serve(s, http.MethodPatch, "/documents/1", `{"Body": "b", "Version": 0}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"ID":1,"Body":"b","Version":1}`)
+
+	// A second client still editing version 0 would overwrite the first one's change.
+	rec = serve(s, http.MethodPut, "/documents/1", `{"Body": "c", "Version": 0}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Code":"conflict"`)
+
+	rec = serve(s, http.MethodPut, "/documents/1", `{"Body": "c", "Version": 1}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"ID":1,"Body":"c","Version":2}`)
+}
+
+type TestAccount struct {
+	UserID string `gorm:"primaryKey"`
+	Name   string
+}
+
+type TestAccountCreate struct {
+	UserID string
+	Name   string
+}
+
+func TestResource_SetPrimaryKey(t *testing.T) {
+	api := &Resource[TestAccount]{Name: "/accounts"}
+	api.SetCreateBindType(TestAccountCreate{})
+	api.SetWriteBindType(TestModelUpdate{})
+	api.SetPrimaryKey("user_id")
+	api.SetIDParser(func(s string) (any, error) {
+		return s, nil
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/accounts", `{"UserID": "u1", "Name": "first"}`)
+
+	rec := serve(s, http.MethodGet, "/accounts/u1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Data":{"UserID":"u1","Name":"first"}`)
+
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodPut, "/accounts/u1", `{"Name": "second"}`).Code)
+	assert.Contains(t, serve(s, http.MethodGet, "/accounts/u1", "").Body.String(), `"Name":"second"`)
+
+	assert.Equal(t, http.StatusOK, serve(s, http.MethodDelete, "/accounts/u1", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(s, http.MethodGet, "/accounts/u1", "").Code)
+}
+
+func TestResource_SetPrimaryKeyLocation(t *testing.T) {
+	api := &Resource[TestSlugModel]{Name: "/slugs"}
+	api.SetCreateBindType(TestSlugModel{})
+	api.SetPrimaryKey("slug")
+	api.SetIDParser(func(s string) (any, error) {
+		return s, nil
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	// The Location points at the slug the entity is looked up by, rather than at the primary key.
+	rec := serve(s, http.MethodPost, "/slugs", `{"Tenant": "a", "Slug": "hello"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "http://example.com/slugs/hello", rec.Header().Get(echo.HeaderLocation))
+
+	rec = serve(s, http.MethodGet, "/slugs/hello", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Slug":"hello"`)
+}
+
+func TestServer_EnableOpenAPI(t *testing.T) {
+	api := &Resource[TestProfile]{Name: "/profiles"}
+	api.SetCreateBindType(TestProfileValidated{})
+	api.SetWriteBindType(TestProfileUpdate{})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+	s.EnableOpenAPI("/openapi.json")
+
+	rec := serve(s, http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var doc map[string]any
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+
+	paths := doc["paths"].(map[string]any)
+	assert.Contains(t, paths, "/profiles")
+	assert.Contains(t, paths["/profiles/{id}"], "put")
+	assert.Contains(t, paths["/profiles"], "post")
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"Name":  map[string]any{"type": "string"},
+			"email": map[string]any{"type": "string"},
+		},
+	}, schemas["TestProfileValidated"])
+	assert.Contains(t, schemas, "TestProfile")
+	assert.Contains(t, schemas, "TestProfileUpdate")
+}
+
+func TestServer_EnableMetrics(t *testing.T) {
+	s, _ := newTestModelServer(t)
+	s.EnableMetrics("/metrics")
+
+	serve(s, http.MethodGet, "/models", "")
+	serve(s, http.MethodGet, "/models/1", "")
+
+	rec := serve(s, http.MethodGet, "/metrics", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `minimal_http_requests_total{method="GET",route="/models",status="200"} 1`)
+	assert.Contains(t, rec.Body.String(), `minimal_http_requests_total{method="GET",route="/models/:id",status="404"} 1`)
+	assert.Contains(t, rec.Body.String(), `minimal_db_queries_total{operation="query",table="test_models"} 2`)
+}
+
+func TestConfig_APIPrefix(t *testing.T) {
+	v1 := &Resource[TestModel]{Name: "/models"}
+	v2 := &Resource[TestModel]{Name: "models"}
+	v2.SetBasePath("/api/v2/")
+
+	config := DevelopmentConfig
+	config.APIPrefix = "/api/v1"
+	s := New(config, []Provider{v1, v2}, []any{})
+	db, err := database.InitInMemory()
+	assert.Nil(t, err)
+	resetDatabase(t)
+	s.db = db
+	s.setup(nil)
+
+	assert.Equal(t, http.StatusOK, serve(&s, http.MethodGet, "/api/v1/models", "").Code)
+	assert.Equal(t, http.StatusOK, serve(&s, http.MethodGet, "/api/v2/models", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(&s, http.MethodGet, "/models", "").Code)
+}
+
+func TestConfig_MergeEnv(t *testing.T) {
+	t.Setenv("DSN", "app.db")
+	t.Setenv("HTTP_PORT", "8080")
+	t.Setenv("DOMAINS", "example.com, www.example.com")
+	t.Setenv("REQUEST_TIMEOUT", "5s")
+
+	config, err := MergeEnv(Config{HttpPort: 80, FriendlyLogging: true, TablePrefix: "app_"})
+	assert.Nil(t, err)
+	assert.Equal(t, "app.db", config.DSN)
+	assert.Equal(t, uint(8080), config.HttpPort)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, config.Domains)
+	assert.Equal(t, 5*time.Second, config.RequestTimeout)
+	assert.True(t, config.FriendlyLogging)
+	assert.Equal(t, "app_", config.TablePrefix)
+
+	t.Setenv("AUTO_TLS", "sometimes")
+	_, err = ConfigFromEnv()
+	assert.EqualError(t, err, `invalid AUTO_TLS: strconv.ParseBool: parsing "sometimes": invalid syntax`)
+}
+
+func TestConfig_EnableGzip(t *testing.T) {
+	get := func(config Config, target string) *httptest.ResponseRecorder {
+		s := New(config, []Provider{}, []any{})
+		s.setup(nil)
+		s.e.GET("/text", func(c echo.Context) error {
+			return c.String(http.StatusOK, strings.Repeat("a", 200))
+		})
+		s.e.GET("/archive", func(c echo.Context) error {
+			return c.Blob(http.StatusOK, "application/zip", make([]byte, 200))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Empty(t, get(DevelopmentConfig, "/text").Header().Get(echo.HeaderContentEncoding))
+
+	config := DevelopmentConfig
+	config.EnableGzip = true
+	config.GzipLevel = gzip.BestCompression
+
+	rec := get(config, "/text")
+	assert.Equal(t, "gzip", rec.Header().Get(echo.HeaderContentEncoding))
+	r, err := gzip.NewReader(rec.Body)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(r)
+	assert.Equal(t, strings.Repeat("a", 200), string(body))
+
+	assert.Empty(t, get(config, "/archive").Header().Get(echo.HeaderContentEncoding))
+}
+
+func TestGzip_ErrorStatus(t *testing.T) {
+	e := echo.New()
+	e.Use(Gzip(GzipConfig{}))
+	e.GET("/denied", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusForbidden, "nope")
+	})
+
+	for target, status := range map[string]int{"/denied": http.StatusForbidden, "/missing": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, status, rec.Code, target)
+	}
+}
+
+func TestConfig_Minify(t *testing.T) {
+	get := func(config Config, target string) *httptest.ResponseRecorder {
+		s := New(config, []Provider{}, []any{})
+		s.setup(nil)
+		s.e.GET("/page", func(c echo.Context) error {
+			return c.HTML(http.StatusOK, "<div>\n    hello\n</div>")
+		})
+		s.e.GET("/data", func(c echo.Context) error {
+			return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, []byte(`{ "a": 1 }`))
+		})
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	assert.Equal(t, "<div>\n    hello\n</div>", get(DevelopmentConfig, "/page").Body.String())
+
+	config := DevelopmentConfig
+	config.Minify = MinifyConfig{Enabled: true}
+	assert.Equal(t, "<div>hello</div>", get(config, "/page").Body.String())
+	assert.Equal(t, `{"a":1}`, get(config, "/data").Body.String())
+
+	config.Minify.ExcludeContentTypes = []string{echo.MIMEApplicationJSON}
+	assert.Equal(t, "<div>hello</div>", get(config, "/page").Body.String())
+	assert.Equal(t, `{ "a": 1 }`, get(config, "/data").Body.String())
+}
+
+func TestMinify_ErrorStatus(t *testing.T) {
+	e := echo.New()
+	e.Use(Minify(MinifyConfig{Enabled: true}))
+	e.GET("/denied", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusForbidden, "nope")
+	})
+
+	for target, status := range map[string]int{"/denied": http.StatusForbidden, "/missing": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, status, rec.Code, target)
+	}
+}
+
+func TestAddMiddlewares_RequestID(t *testing.T) {
+	e := echo.New()
+	AddMiddlewares(e)
+	e.GET("/fail", func(c echo.Context) error {
+		return res.FailCode(c, http.StatusBadRequest, errors.New("failed"))
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	id := rec.Header().Get(echo.HeaderXRequestID)
+	assert.NotEmpty(t, id)
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"RequestID":%q`, id))
+
+	// IDs from upstream services are kept, so a request can be traced across them.
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(echo.HeaderXRequestID, "upstream")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, "upstream", rec.Header().Get(echo.HeaderXRequestID))
+	assert.Contains(t, rec.Body.String(), `"RequestID":"upstream"`)
+}
+
+func TestConfig_Replicas(t *testing.T) {
+	replica, err := gorm.Open(sqlite.Open("file:replica?mode=memory&cache=shared"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, replica.AutoMigrate(&TestModel{}))
+	assert.NoError(t, replica.Create(&TestModel{Name: "replicated"}).Error)
+
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.SetWriteBindType(TestModelUpdate{})
+	config := DevelopmentConfig
+	config.DSN = "file:primary?mode=memory&cache=shared"
+	config.Replicas = []string{"file:replica?mode=memory&cache=shared"}
+	s := New(config, []Provider{api}, []any{})
+	s.initDatabase()
+	s.setup(nil)
+	resetDatabase(t)
+
+	// The primary is migrated even though the replica already has the table.
+	assert.True(t, s.db.Clauses(dbresolver.Write).Migrator().HasTable(&TestModel{}))
+
+	rec := serve(&s, http.MethodPost, "/models", `{"Name": "written"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var written TestModel
+	assert.NoError(t, s.db.Clauses(dbresolver.Write).First(&written).Error)
+	assert.Equal(t, "written", written.Name)
+
+	rec = serve(&s, http.MethodGet, "/models/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Name":"replicated"`)
+
+	rec = serve(&s, http.MethodPut, "/models/1", `{"Name": "updated"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, s.db.Clauses(dbresolver.Write).First(&written).Error)
+	assert.Equal(t, "updated", written.Name)
+}
+
+func TestResource_UpdatedSince(t *testing.T) {
+	api := &Resource[TestSoftModel]{Name: "/soft"}
+	api.SetCreateBindType(TestModelUpdate{})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	serve(s, http.MethodPost, "/soft", `{"Name": "old"}`)
+	serve(s, http.MethodPost, "/soft", `{"Name": "new"}`)
+
+	cutoff := time.Now().Add(-time.Hour)
+	assert.NoError(t, s.db.Model(&TestSoftModel{}).Where("name = ?", "old").
+		UpdateColumn("updated_at", cutoff.Add(-time.Hour)).Error)
+
+	since := url.QueryEscape(cutoff.Format(time.RFC3339))
+	rec := serve(s, http.MethodGet, "/soft?updated_since="+since, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Name":"new"`)
+	assert.NotContains(t, rec.Body.String(), `"Name":"old"`)
+
+	rec = serve(s, http.MethodGet, "/soft/count?updated_since="+since, "")
+	assert.Contains(t, rec.Body.String(), `"Count":1`)
+
+	rec = serve(s, http.MethodGet, "/soft?updated_since=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	api.SetTimestampField("modified_at")
+	rec = serve(s, http.MethodGet, "/soft?updated_since="+since, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	api.SetTimestampField("created_at")
+	rec = serve(s, http.MethodGet, "/soft?updated_since="+since, "")
+	assert.Contains(t, rec.Body.String(), `"Name":"old"`)
+}
+
+func TestResource_SetSearchableFields(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetSearchableFields("name")
+
+	for _, name := range []string{"Alice Smith", "Bob Smithson", "Carol", "100% Carol", "carol_jones"} {
+		serve(s, http.MethodPost, "/models", fmt.Sprintf(`{"Name": %q}`, name))
+	}
+
+	names := func(target string) []string {
+		rec := serve(s, http.MethodGet, target, "")
+		assert.Equal(t, http.StatusOK, rec.Code, target)
+
+		var body res.ModelResponse[[]TestModel]
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+		var names []string
+		for _, m := range body.Data {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+
+	assert.ElementsMatch(t, []string{"Alice Smith", "Bob Smithson"}, names("/models?q=SMITH"))
+	assert.ElementsMatch(t, []string{"100% Carol"}, names("/models?q="+url.QueryEscape("0%")))
+	assert.ElementsMatch(t, []string{"carol_jones"}, names("/models?q=l_"))
+	assert.Len(t, names("/models"), 5)
+
+	rec := serve(s, http.MethodGet, "/models/count?q=carol", "")
+	assert.Contains(t, rec.Body.String(), `"Count":3`)
+}
+
+func TestResource_ListAndGetTransformers(t *testing.T) {
+	type Summary struct {
+		Label string
+	}
+
+	s, api := newTestModelServer(t)
+	api.SetListTransformer(func(c echo.Context, items []TestModel) (any, error) {
+		summaries := make([]Summary, len(items))
+		for i, item := range items {
+			summaries[i] = Summary{Label: strings.ToUpper(item.Name)}
+		}
+		return summaries, nil
+	})
+	api.SetGetTransformer(func(c echo.Context, item *TestModel) (any, error) {
+		if item.Name == "secret" {
+			return nil, ErrorNoResourceAccess
+		}
+		return Summary{Label: item.Name + "!"}, nil
+	})
+
+	serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	serve(s, http.MethodPost, "/models", `{"Name": "secret"}`)
+
+	rec := serve(s, http.MethodGet, "/models", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"Label":"FIRST"},{"Label":"SECRET"}]`, string(decodeData(t, rec)))
+
+	rec = serve(s, http.MethodGet, "/models/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"Label":"first!"}`, string(decodeData(t, rec)))
+
+	rec = serve(s, http.MethodGet, "/models/2", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	api.SetListTransformer(func(c echo.Context, items []TestModel) (any, error) {
+		return nil, errors.New("broken")
+	})
+	rec = serve(s, http.MethodGet, "/models", "")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "broken")
+}
+
+func TestResource_TransformedExportAndWrites(t *testing.T) {
+	type Summary struct {
+		Label string
+	}
+
+	api := &Resource[TestModel]{Name: "/models"}
+	api.SetCreateBindType(TestModelUpdate{})
+	api.SetWriteBindType(TestModelUpdate{})
+	api.SetSearchableFields("name")
+	api.SetReturnCreatedEntity(true)
+	api.SetReturnUpdatedEntity(true)
+	api.SetReturnDeleted(true)
+	api.EnableExport(10)
+	api.SetGetTransformer(func(c echo.Context, item *TestModel) (any, error) {
+		return Summary{Label: strings.ToUpper(item.Name)}, nil
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	rec := serve(s, http.MethodPost, "/models", `{"Name": "first"}`)
+	assert.JSONEq(t, `{"Label":"FIRST"}`, string(decodeData(t, rec)))
+	serve(s, http.MethodPost, "/models", `{"Name": "second"}`)
+
+	rec = serve(s, http.MethodPut, "/models/1", `{"Name": "renamed"}`)
+	assert.JSONEq(t, `{"Label":"RENAMED"}`, string(decodeData(t, rec)))
+
+	rec = serve(s, http.MethodGet, "/models/export", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `"Name"`)
+	assert.Equal(t, "{\"Label\":\"RENAMED\"}\n{\"Label\":\"SECOND\"}\n", rec.Body.String())
+
+	// The export lists what list all would, so searches apply as well.
+	rec = serve(s, http.MethodGet, "/models/export?q=sec", "")
+	assert.Equal(t, "{\"Label\":\"SECOND\"}\n", rec.Body.String())
+
+	api.SetListTransformer(func(c echo.Context, items []TestModel) (any, error) {
+		summaries := make([]Summary, len(items))
+		for i, item := range items {
+			summaries[i] = Summary{Label: item.Name + "!"}
+		}
+		return summaries, nil
+	})
+	rec = serve(s, http.MethodGet, "/models/export", "")
+	assert.Equal(t, "{\"Label\":\"renamed!\"}\n{\"Label\":\"second!\"}\n", rec.Body.String())
+
+	rec = serve(s, http.MethodDelete, "/models/2", "")
+	assert.JSONEq(t, `{"Label":"SECOND"}`, string(decodeData(t, rec)))
+}
+
+// decodeData returns the Data of a response written by the res package.
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder) json.RawMessage {
+	var body res.ModelResponse[json.RawMessage]
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body.Data
+}
+
+func TestResource_SetCursorPagination(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetCursorPagination("id")
+	api.SetPagination(2, 10)
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		serve(s, http.MethodPost, "/models", fmt.Sprintf(`{"Name": %q}`, name))
+	}
+
+	page := func(target string) ([]string, string) {
+		rec := serve(s, http.MethodGet, target, "")
+		assert.Equal(t, http.StatusOK, rec.Code, target)
+
+		var body res.ModelResponse[struct {
+			Items      []TestModel
+			NextCursor string
+		}]
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+		var names []string
+		for _, m := range body.Data.Items {
+			names = append(names, m.Name)
+		}
+		return names, body.Data.NextCursor
+	}
+
+	names, next := page("/models")
+	assert.Equal(t, []string{"a", "b"}, names)
+	assert.NotEmpty(t, next)
+
+	// Deleting seen entities doesn't shift the following pages.
+	serve(s, http.MethodDelete, "/models/1", "")
+
+	names, next = page("/models?after=" + next)
+	assert.Equal(t, []string{"c", "d"}, names)
+
+	names, next = page("/models?per_page=5&after=" + next)
+	assert.Equal(t, []string{"e"}, names)
+	assert.Empty(t, next)
+
+	rec := serve(s, http.MethodGet, "/models?after=not-a-cursor", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	api.SetSortableFields("name")
+	rec = serve(s, http.MethodGet, "/models?sort=name", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+type TestPrivate struct {
+	ID      uint
+	Name    string
+	Private bool
+}
+
+func TestResource_SelectableFieldsLoadWholeEntities(t *testing.T) {
+	api := &Resource[TestPrivate]{Name: "/privs"}
+	api.SetSelectableFields("id", "name")
+	api.CanListById(func(c echo.Context, entity TestPrivate) bool {
+		return !entity.Private
+	})
+	api.SetScope(func(c echo.Context, q *gorm.DB) *gorm.DB {
+		return q.Where("private = ?", false)
+	})
+
+	s := NewTestServer([]Provider{api}, []any{})
+	resetDatabase(t)
+
+	assert.NoError(t, s.db.Create(&[]TestPrivate{{Name: "public"}, {Name: "other"}, {Name: "third"}}).Error)
+	assert.NoError(t, s.db.Create(&TestPrivate{Name: "secret", Private: true}).Error)
+
+	// The access check sees the whole entity, even when its fields weren't asked for.
+	api.SetScope(nil)
+	rec := serve(s, http.MethodGet, "/privs/4?fields=name", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "secret")
+
+	rec = serve(s, http.MethodGet, "/privs/1?fields=name", "")
+	assert.Contains(t, rec.Body.String(), `"Data":{"Name":"public"}`)
+
+	// Deleting looks the entity up by its primary key, which has to be loaded.
+	api.SetScope(func(c echo.Context, q *gorm.DB) *gorm.DB {
+		return q.Where("private = ?", false)
+	})
+	rec = serve(s, http.MethodDelete, "/privs/1?fields=name", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var count int64
+	assert.NoError(t, s.db.Model(&TestPrivate{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestResource_SetCursorPaginationMaxListSize(t *testing.T) {
+	s, api := newTestModelServer(t)
+	api.SetCursorPagination("id")
+	s.Echo().Use(MaxListSize(2))
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		serve(s, http.MethodPost, "/models", fmt.Sprintf(`{"Name": %q}`, name))
+	}
+
+	// Every entity is reached, even though the pages are capped below the default page size.
+	var names []string
+	target := "/models"
+	for i := 0; i < 5; i++ {
+		var body res.ModelResponse[CursorResponse]
+		assert.NoError(t, json.Unmarshal(serve(s, http.MethodGet, target, "").Body.Bytes(), &body))
+
+		items, _ := body.Data.Items.([]any)
+		assert.LessOrEqual(t, len(items), 2)
+		for _, item := range items {
+			names = append(names, item.(map[string]any)["Name"].(string))
+		}
+
+		if body.Data.NextCursor == "" {
+			break
+		}
+		target = "/models?after=" + body.Data.NextCursor
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+}
+
+func TestForUpdate(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	assert.NoError(t, err)
+
+	var model TestModel
+	stmt := forUpdate(db).First(&model, 1).Statement
+	assert.Contains(t, stmt.SQL.String(), "FOR UPDATE")
+
+	// The lock doesn't leak into other queries on the same handle.
+	stmt = db.First(&model, 1).Statement
+	assert.NotContains(t, stmt.SQL.String(), "FOR UPDATE")
+
+	sqliteDB, err := database.InitInMemory()
+	assert.NoError(t, err)
+	resetDatabase(t)
+	assert.Same(t, sqliteDB, forUpdate(sqliteDB))
+}
@@ -0,0 +1,86 @@
+package minimal
+
+import (
+	"errors"
+	"github.com/kaiaverkvist/minimal/res"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+	"net/http"
+	"reflect"
+)
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// restoreById undoes the soft deletion of an entity. Restoring is allowed for whoever may delete it.
+func (r *Resource[T]) restoreById(c echo.Context) error {
+	id, err := r.parseID(c)
+	if err != nil {
+		return r.fail(c, OperationRestoreById, http.StatusBadRequest, err)
+	}
+
+	// The lookup, access check and restore share a transaction, which also keeps the lookup on the primary database
+	// when reads go to replicas.
+	var result T
+	field := softDeleteField(reflect.TypeOf(result))
+	err = r.query(c).Transaction(func(tx *gorm.DB) error {
+		q := r.scoped(c, tx.Unscoped())
+		if err := q.First(&result, r.byId(id)).Error; err != nil {
+			return err
+		}
+
+		if r.canDeleteById != nil && !r.canDeleteById(c, result) {
+			return ErrorNoResourceAccess
+		}
+
+		return q.Model(&result).Update(field, nil).Error
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return r.fail(c, OperationRestoreById, http.StatusNotFound, ErrorNoResourceFound)
+	}
+
+	if errors.Is(err, ErrorNoResourceAccess) {
+		return r.fail(c, OperationRestoreById, http.StatusForbidden, ErrorNoResourceAccess)
+	}
+
+	if err != nil {
+		log.Errorf("Could not restore by id for resource %s: %s", reflect.TypeOf(r), err)
+		return r.fail(c, OperationRestoreById, http.StatusInternalServerError, ErrorDatabase)
+	}
+
+	reflect.ValueOf(&result).Elem().FieldByName(field).Set(reflect.Zero(deletedAtType))
+	item, err := transformed(r, c, &result, r.getTransformer)
+	if err != nil {
+		return r.failTransform(c, OperationRestoreById, err)
+	}
+
+	return res.Ok(c, item)
+}
+
+// softDeleteField returns the name of the gorm.DeletedAt field of t, which may be promoted from an embedded struct
+// such as gorm.Model. Returns an empty string for types without soft deletion.
+func softDeleteField(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type == deletedAtType {
+			return field.Name
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if name := softDeleteField(field.Type); name != "" {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,37 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// Escapes the wildcards of LIKE patterns. Not a backslash, which MySQL would also treat as an escape in the literal.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// searched restricts q to the entities where any of the searchable columns contains ?q=, ignoring case. Wildcards in
+// the search are matched literally.
+func (r *Resource[T]) searched(c echo.Context, q *gorm.DB) *gorm.DB {
+	search := c.QueryParam("q")
+	if search == "" || len(r.searchableFields) == 0 {
+		return q
+	}
+
+	// ILIKE is specific to Postgres, elsewhere both sides are lowercased instead.
+	sql := "LOWER(?) LIKE ? ESCAPE '!'"
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(search)) + "%"
+	if q.Dialector.Name() == "postgres" {
+		sql = "? ILIKE ? ESCAPE '!'"
+	}
+
+	conditions := make([]clause.Expression, 0, len(r.searchableFields))
+	for _, column := range r.searchableFields {
+		conditions = append(conditions, clause.Expr{
+			SQL:  sql,
+			Vars: []any{clause.Column{Table: clause.CurrentTable, Name: column}, pattern},
+		})
+	}
+
+	return q.Where(clause.Or(conditions...))
+}
@@ -1,9 +1,13 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/labstack/gommon/log"
@@ -11,24 +15,53 @@ import (
 	"golang.org/x/crypto/acme/autocert"
 )
 
-func Start(e *echo.Echo, port string, autoTls bool, cert string, pkey string, domains []string) {
+// Limits hardens the http server against slow or abusive clients. Zero values are left unset, except ReadTimeout
+// which defaults to 30 seconds.
+type Limits struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+}
+
+// DefaultShutdownTimeout is how long in-flight requests get to finish when shutting down, unless configured.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Start serves e, blocking until the server is shut down or fails to start.
+func Start(e *echo.Echo, port string, autoTls bool, cert string, pkey string, domains []string, limits Limits) {
 	if autoTls {
-		startAutoTLS(e, port, cert, pkey, domains)
+		startAutoTLS(e, port, cert, pkey, domains, limits)
 		return
 	}
 
-	startInsecure(e, port)
+	startInsecure(e, port, limits)
 	return
 }
 
-func startInsecure(e *echo.Echo, port string) {
+// apply sets the limits on s.
+func (l Limits) apply(s *http.Server) {
+	s.ReadTimeout = l.ReadTimeout
+	if s.ReadTimeout == 0 {
+		s.ReadTimeout = 30 * time.Second
+	}
+
+	s.ReadHeaderTimeout = l.ReadHeaderTimeout
+	s.WriteTimeout = l.WriteTimeout
+	s.IdleTimeout = l.IdleTimeout
+	s.MaxHeaderBytes = l.MaxHeaderBytes
+}
+
+func startInsecure(e *echo.Echo, port string, limits Limits) {
+	limits.apply(e.Server)
+
 	err := e.Start(port)
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		log.Error("Unable to start server in insecure mode > ", err)
 	}
 }
 
-func startAutoTLS(e *echo.Echo, port string, cert string, pkey string, domains []string) {
+func startAutoTLS(e *echo.Echo, port string, cert string, pkey string, domains []string, limits Limits) {
 	dirCache := autocert.DirCache("/var/www/.cache")
 	e.AutoTLSManager.Cache = dirCache
 	autoTLSManager := autocert.Manager{
@@ -37,17 +70,45 @@ func startAutoTLS(e *echo.Echo, port string, cert string, pkey string, domains [
 		Cache:      dirCache,
 		HostPolicy: autocert.HostWhitelist(domains...),
 	}
-	s := http.Server{
-		Addr:    port,
-		Handler: e,
-		TLSConfig: &tls.Config{
-			GetCertificate: autoTLSManager.GetCertificate,
-			NextProtos:     []string{acme.ALPNProto},
-		},
-		ReadTimeout: 30 * time.Second,
+	// Echo's own TLS server is used, so that shutting echo down also shuts this down.
+	s := e.TLSServer
+	s.Addr = port
+	s.Handler = e
+	s.TLSConfig = &tls.Config{
+		GetCertificate: autoTLSManager.GetCertificate,
+		NextProtos:     []string{acme.ALPNProto},
 	}
+	limits.apply(s)
 
 	if err := s.ListenAndServeTLS(cert, pkey); err != http.ErrServerClosed {
 		e.Logger.Fatal("Unable to start server in AutoTLS mode > ", err)
 	}
 }
+
+// ShutdownOnSignal blocks until SIGINT or SIGTERM is received, or until done is closed because the server stopped by
+// itself. On a signal, e stops accepting new connections and in-flight requests get up to timeout to finish.
+func ShutdownOnSignal(e *echo.Echo, timeout time.Duration, done <-chan struct{}) {
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case <-done:
+		return
+	case sig := <-signals:
+		log.Info("Shutting down on ", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := e.Shutdown(ctx); err != nil {
+		log.Error("Unable to shut down gracefully > ", err)
+	}
+
+	<-done
+}
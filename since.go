@@ -0,0 +1,41 @@
+package minimal
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// updatedSince restricts q to the entities changed at or after ?updated_since=, an RFC 3339 timestamp, so clients can
+// sync incrementally rather than listing everything every time.
+func (r *Resource[T]) updatedSince(c echo.Context, q *gorm.DB) (*gorm.DB, error) {
+	value := c.QueryParam("updated_since")
+	if value == "" {
+		return q, nil
+	}
+
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: updated_since must be an RFC 3339 timestamp, e.g. 2024-01-01T00:00:00Z", ErrorInvalidData)
+	}
+
+	name := r.timestampField
+	if name == "" {
+		name = "updated_at"
+	}
+
+	// Checked up front, as a missing column would otherwise fail the query with a database error.
+	stmt := &gorm.Statement{DB: q}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+
+	field := stmt.Schema.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		return nil, fmt.Errorf("%w: can't filter on updated_since", ErrorInvalidData)
+	}
+
+	return q.Where(clause.Gte{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: since}), nil
+}
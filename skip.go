@@ -0,0 +1,39 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+/*
+Skip wraps a server level middleware so that it isn't run for requests where skipper returns true. This lets internal
+routes such as health checks and metrics opt out of e.g. auth or rate limiting:
+
+	e.Use(minimal.Skip(auth, minimal.PublicRoutes("/healthz", "/metrics")))
+*/
+func Skip(m echo.MiddlewareFunc, skipper middleware.Skipper) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		wrapped := m(next)
+		return func(c echo.Context) error {
+			if skipper(c) {
+				return next(c)
+			}
+
+			return wrapped(c)
+		}
+	}
+}
+
+// PublicRoutes is a skipper matching requests routed to one of routes, which are given as registered with echo,
+// e.g. "/status/:service". It can also be used as the Skipper of echo's own middleware configs.
+func PublicRoutes(routes ...string) middleware.Skipper {
+	return func(c echo.Context) bool {
+		for _, route := range routes {
+			if c.Path() == route {
+				return true
+			}
+		}
+
+		return false
+	}
+}
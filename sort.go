@@ -0,0 +1,33 @@
+package minimal
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+// sorted orders q by the column in ?sort=, in the direction in ?order=, which is either asc or desc. Only sortable
+// columns are accepted, so clients can't inject SQL into the ORDER BY clause.
+func (r *Resource[T]) sorted(c echo.Context, q *gorm.DB) (*gorm.DB, error) {
+	column := c.QueryParam("sort")
+	if column == "" {
+		return q, nil
+	}
+
+	if !contains(r.sortableFields, column) {
+		return nil, fmt.Errorf("%w: can't sort on %s", ErrorInvalidData, column)
+	}
+
+	var desc bool
+	switch strings.ToLower(c.QueryParam("order")) {
+	case "", "asc":
+	case "desc":
+		desc = true
+	default:
+		return nil, fmt.Errorf("%w: order must be asc or desc", ErrorInvalidData)
+	}
+
+	return q.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc}), nil
+}
@@ -0,0 +1,53 @@
+package minimal
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+const tenantContextKey = "minimal.tenant"
+
+var (
+	ErrorInvalidTenant  = errors.New("bad tenant")
+	ErrorNoTenantAccess = errors.New("no tenant access")
+)
+
+/*
+TenantMiddleware resolves the tenant for every request and stores it in the echo context, where it can be read back
+using Tenant. The resolver typically looks at the subdomain, a header or a JWT claim:
+
+	e.Use(minimal.TenantMiddleware(func(c echo.Context) (string, error) {
+		tenant := c.Request().Header.Get("X-Tenant")
+		if tenant == "" {
+			return "", minimal.ErrorInvalidTenant
+		}
+		return tenant, nil
+	}))
+
+When the resolver fails the request is stopped. Returning an error wrapping ErrorNoTenantAccess responds with 401,
+any other error responds with 400.
+*/
+func TenantMiddleware(resolver func(c echo.Context) (string, error)) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tenant, err := resolver(c)
+			if err != nil {
+				if errors.Is(err, ErrorNoTenantAccess) {
+					return failCode(c, http.StatusUnauthorized, ErrorNoTenantAccess)
+				}
+
+				return failCode(c, http.StatusBadRequest, ErrorInvalidTenant)
+			}
+
+			c.Set(tenantContextKey, tenant)
+			return next(c)
+		}
+	}
+}
+
+// Tenant returns the tenant resolved by TenantMiddleware, or an empty string if there is none.
+func Tenant(c echo.Context) string {
+	tenant, _ := c.Get(tenantContextKey).(string)
+	return tenant
+}
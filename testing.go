@@ -0,0 +1,38 @@
+package minimal
+
+import (
+	"github.com/kaiaverkvist/minimal/database"
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+/*
+NewTestServer creates a Server backed by a fresh in-memory SQLite database, with the standard middlewares and the
+providers registered. It doesn't listen on a port, instead requests can be fired at it directly:
+
+	s := minimal.NewTestServer([]minimal.Provider{NewTestResource()}, []any{})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tests", nil))
+*/
+func NewTestServer(providers []Provider, models []any) *Server {
+	db, err := database.InitInMemory()
+	if err != nil {
+		log.Fatal("Unable to create in-memory database: ", err)
+	}
+
+	return NewTestServerOn(db, providers, models)
+}
+
+// NewTestServerOn is NewTestServer, but backed by db rather than a database of its own. The caller owns db, and is
+// responsible for closing it.
+func NewTestServerOn(db *gorm.DB, providers []Provider, models []any) *Server {
+	s := New(DevelopmentConfig, providers, models)
+	s.e.HideBanner = true
+	s.db = db
+
+	s.migrate()
+	s.setup(nil)
+
+	return &s
+}
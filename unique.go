@@ -0,0 +1,53 @@
+package minimal
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrorConflict = errors.New("conflict")
+)
+
+// checkUnique looks for an existing entity sharing the values of every field in one of the unique sets with model.
+// The returned error wraps ErrorConflict and names the conflicting fields when one is found.
+func checkUnique[T any](q *gorm.DB, model *T, uniqueSets [][]string) error {
+	if len(uniqueSets) == 0 {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: q}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(model).Elem()
+	for _, fields := range uniqueSets {
+		var conditions []clause.Expression
+		for _, name := range fields {
+			field := stmt.Schema.LookUpField(name)
+			if field == nil {
+				return fmt.Errorf("unknown unique field %s", name)
+			}
+
+			v, _ := field.ValueOf(value)
+			conditions = append(conditions, clause.Eq{Column: clause.Column{Name: field.DBName}, Value: v})
+		}
+
+		var existing T
+		tx := q.Clauses(conditions...).Limit(1).Find(&existing)
+		if tx.Error != nil {
+			return tx.Error
+		}
+
+		if tx.RowsAffected > 0 {
+			return fmt.Errorf("%w: %s must be unique together", ErrorConflict, strings.Join(fields, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package minimal
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"strings"
+)
+
+const baseURLContextKey = "minimal.baseURL"
+
+// BaseURL makes ExternalURL build links on top of baseURL, which is the public URL of the application when it runs
+// behind a reverse proxy that adds or strips a path prefix.
+func BaseURL(baseURL string) echo.MiddlewareFunc {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(baseURLContextKey, baseURL)
+			return next(c)
+		}
+	}
+}
+
+// ExternalURL returns the public URL for path, which should be a path as routed by echo. It uses the base URL set up
+// through Config.ExternalBaseURL, falling back to the scheme and host of the request.
+func ExternalURL(c echo.Context, path string) string {
+	if baseURL, ok := c.Get(baseURLContextKey).(string); ok {
+		return baseURL + path
+	}
+
+	return fmt.Sprintf("%s://%s%s", c.Scheme(), c.Request().Host, path)
+}
@@ -0,0 +1,84 @@
+package minimal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// Shared by resources which haven't been given a validator of their own.
+var defaultValidator = newValidator()
+
+// newValidator returns a validator reporting fields by the names clients send them as.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, skip := jsonFieldName(field)
+		if skip {
+			return ""
+		}
+		return name
+	})
+
+	return v
+}
+
+// validate checks bound against its validate struct tags. Failures are returned as FieldErrors, keyed by field.
+func (r *Resource[T]) validate(bound any) error {
+	v := r.validator
+	if v == nil {
+		v = defaultValidator
+	}
+
+	return fieldErrors(v.Struct(bound))
+}
+
+// validatePartial is validate, but only checks the given fields of bound, such as those sent in a PATCH.
+func (r *Resource[T]) validatePartial(bound any, fields []string) error {
+	v := r.validator
+	if v == nil {
+		v = defaultValidator
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return fieldErrors(v.StructPartial(bound, fields...))
+}
+
+// fieldErrors maps the errors of a validation to FieldErrors, keyed by field.
+func fieldErrors(err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		// Bind types which aren't structs have nothing to validate.
+		return nil
+	}
+
+	fieldErrs := FieldErrors{}
+	for _, fe := range validationErrs {
+		field := fe.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+
+		fieldErrs[field] = validationMessage(fe)
+	}
+
+	return fieldErrs
+}
+
+// validationMessage explains why a field failed validation.
+func validationMessage(fe validator.FieldError) string {
+	if fe.Tag() == "required" {
+		return "is required"
+	}
+
+	if fe.Param() != "" {
+		return fmt.Sprintf("must satisfy %s=%s", fe.Tag(), fe.Param())
+	}
+
+	return fmt.Sprintf("must satisfy %s", fe.Tag())
+}
@@ -0,0 +1,89 @@
+package minimal
+
+import (
+	"github.com/labstack/echo/v4"
+	"reflect"
+	"strings"
+)
+
+// maskFields converts v (a struct, pointer to struct or slice of those) into a JSON-friendly representation that only
+// contains the fields for which visible returns true. Field names are the ones that would be used when encoding JSON.
+func maskFields(c echo.Context, v any, visible func(c echo.Context, field string) bool) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return v
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return v
+		}
+
+		result := make([]any, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			result[i] = maskFields(c, rv.Index(i).Interface(), visible)
+		}
+		return result
+	case reflect.Struct:
+		result := map[string]any{}
+		maskStruct(c, rv, visible, result)
+		return result
+	}
+
+	return v
+}
+
+func maskStruct(c echo.Context, rv reflect.Value, visible func(c echo.Context, field string) bool, out map[string]any) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, hasTagName, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		// Embedded structs are flattened by encoding/json, so we do the same.
+		if field.Anonymous && !hasTagName {
+			fv := rv.Field(i)
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+
+			if fv.Kind() == reflect.Struct {
+				maskStruct(c, fv, visible, out)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if visible(c, name) {
+			out[name] = rv.Field(i).Interface()
+		}
+	}
+}
+
+// jsonFieldName returns the name used for the field when encoding JSON, whether it came from a tag, and whether
+// the field is skipped altogether.
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return field.Name, false, false
+	}
+
+	return name, true, false
+}